@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// ErrMiss is returned by Get when nothing is cached under a key.
+var ErrMiss = errors.New("not in cache")
+
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Store keeps raw API responses on disk, one file per key, so they can be
+// reused by later runs and by other commands. The file's modification time
+// records when the response was fetched.
+type Store struct {
+	Dir string
+}
+
+func New(dir string) *Store {
+	return &Store{Dir: dir}
+}
+
+// DefaultDir returns the cache directory under the user's cache directory.
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("could not find user's cache directory: %w", err)
+	}
+	return filepath.Join(dir, "fueltracker"), nil
+}
+
+// Path returns the file a key is stored in.
+func (s *Store) Path(key string) string {
+	return filepath.Join(s.Dir, unsafeChars.ReplaceAllString(key, "_")+".json")
+}
+
+// Get returns the data stored under key and when it was stored.
+func (s *Store) Get(key string) ([]byte, time.Time, error) {
+	path := s.Path(key)
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, time.Time{}, ErrMiss
+	}
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return data, info.ModTime(), nil
+}
+
+// Put stores data under key, replacing anything already there.
+func (s *Store) Put(key string, data []byte) error {
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return fmt.Errorf("creating cache directory: %w", err)
+	}
+
+	// Write to a temporary file first so a concurrent reader never sees a
+	// partial response.
+	tmp, err := os.CreateTemp(s.Dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.Path(key))
+}
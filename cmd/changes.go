@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/poolski/fueltracker/config"
+	"github.com/poolski/fueltracker/fueldata"
+	"github.com/poolski/fueltracker/history"
+	"github.com/poolski/fueltracker/output"
+	"github.com/spf13/cobra"
+)
+
+// changesCmd represents the changes command
+var changesCmd = &cobra.Command{
+	Use:   "changes",
+	Short: "Show what has changed since the area was last looked up",
+	Long: `changes -p "SW1A 1AA" --min-drop 2
+
+Looks up prices and compares them with the previous lookup of the same
+postcode, listing price rises and drops, new stations, stations which have
+gone and fuels which are no longer sold. Set changes.min_rise_pence and
+changes.min_drop_pence in the config, or use --min-rise and --min-drop, to
+leave out small price changes.`,
+	RunE: doChanges,
+}
+
+func doChanges(cmd *cobra.Command, args []string) error {
+	fuel, err := fuelFlag(cmd)
+	if err != nil {
+		return err
+	}
+	if fuel == fueldata.FuelTypeEV {
+		return errors.New("EV charging has no prices to compare")
+	}
+	format, err := changesOutputFlag(cmd)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	opts := fueldata.QueryOpts{
+		FuelType: fuel,
+	}
+	if err := locationFlags(cmd, cfg, &opts); err != nil {
+		return err
+	}
+
+	c, err := fueldata.FromConfig(cfg)
+	if err != nil {
+		return err
+	}
+	res, err := c.Lookup(cmd.Context(), opts)
+	if err != nil {
+		return err
+	}
+	if err := printChanges(cmd.OutOrStdout(), cmd.ErrOrStderr(), format, res, compareFlags(cmd, cfg, fuel)); err != nil {
+		return err
+	}
+	notes := cmd.OutOrStdout()
+	if !format.IsTable() {
+		notes = cmd.ErrOrStderr()
+	}
+	printCacheNote(notes, cfg, res)
+	return nil
+}
+
+// compareFlags returns the options for comparing lookups, from the config
+// and any --min-rise and --min-drop flags.
+func compareFlags(cmd *cobra.Command, cfg *config.Config, fuel string) history.CompareOpts {
+	opts := history.CompareOpts{
+		MinRise: cfg.Changes.MinRisePence,
+		MinDrop: cfg.Changes.MinDropPence,
+	}
+	if cmd.Flags().Changed("min-rise") {
+		opts.MinRise, _ = cmd.Flags().GetFloat64("min-rise")
+	}
+	if cmd.Flags().Changed("min-drop") {
+		opts.MinDrop, _ = cmd.Flags().GetFloat64("min-drop")
+	}
+	if fuel != fueldata.FuelTypeAll {
+		opts.FuelType = fuel
+	}
+	return opts
+}
+
+func changesOutputFlag(cmd *cobra.Command) (output.Format, error) {
+	outputFlag, _ := cmd.Flags().GetString("output")
+	return output.ParseFormat(outputFlag)
+}
+
+// printChanges writes what has changed since the previous lookup of the area
+// res is for. Notes go to errW unless the output is a table, so they don't
+// get mixed up with the data.
+func printChanges(w, errW io.Writer, format output.Format, res *fueldata.Result, opts history.CompareOpts) error {
+	notes := w
+	if !format.IsTable() {
+		notes = errW
+	}
+	if res.Previous == nil {
+		fmt.Fprintln(notes, "Nothing to compare with yet, the prices have been saved for next time")
+		return nil
+	}
+
+	since := res.Previous.TakenAt.Local().Format("02/01/2006 15:04")
+	changes := res.Changes(opts)
+	if len(changes) == 0 {
+		fmt.Fprintf(notes, "No changes since %s\n", since)
+		if format.IsTable() {
+			return nil
+		}
+	} else {
+		fmt.Fprintf(notes, "Changes since %s:\n", since)
+	}
+	return output.RenderChanges(w, format, res.Previous, res.Snapshot, changes)
+}
+
+// addCompareFlags adds the flags read by compareFlags.
+func addCompareFlags(cmd *cobra.Command) {
+	cmd.Flags().Float64("min-rise", 0, "only show price rises of at least this many pence, overriding changes.min_rise_pence")
+	cmd.Flags().Float64("min-drop", 0, "only show price drops of at least this many pence, overriding changes.min_drop_pence")
+}
+
+func init() {
+	rootCmd.AddCommand(changesCmd)
+	addCompareFlags(changesCmd)
+	changesCmd.Flags().StringP("output", "o", string(output.Table), "output format: 'table', 'json', 'ndjson', 'csv' or 'yaml'")
+}
@@ -41,6 +41,11 @@ func generateCmdRunE(cmd *cobra.Command, args []string) error {
 			}
 			continue
 		}
+		// Anything else that isn't a plain string is optional and has to
+		// be set by editing the file.
+		if field.Type.Kind() != reflect.String {
+			continue
+		}
 		value, err := promptForValue(field.Name)
 		if err != nil {
 			return err
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/poolski/fueltracker/fueldata"
+)
+
+// Process exit codes, so wrappers like cron jobs can tell failures apart.
+const (
+	exitError             = 1
+	exitInvalidAPIKey     = 3
+	exitNoCredit          = 4
+	exitInvalidPostcode   = 5
+	exitNoStations        = 6
+	exitMalformedResponse = 7
+	exitStationMatch      = 8
+	exitNoPrice           = 9
+)
+
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, fueldata.ErrInvalidAPIKey):
+		return exitInvalidAPIKey
+	case errors.Is(err, fueldata.ErrNoCredit):
+		return exitNoCredit
+	case errors.Is(err, fueldata.ErrInvalidPostcode):
+		return exitInvalidPostcode
+	case errors.Is(err, fueldata.ErrNoStations):
+		return exitNoStations
+	case errors.Is(err, fueldata.ErrMalformedResponse):
+		return exitMalformedResponse
+	case errors.Is(err, fueldata.ErrStationNotFound), errors.Is(err, fueldata.ErrAmbiguousStation):
+		return exitStationMatch
+	case errors.Is(err, fueldata.ErrNoPrice):
+		return exitNoPrice
+	}
+	return exitError
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/poolski/fueltracker/fueldata"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"other", errors.New("boom"), exitError},
+		{"invalid key", &fueldata.APIError{Provider: "ukvd", Kind: fueldata.ErrInvalidAPIKey}, exitInvalidAPIKey},
+		{"no credit", &fueldata.APIError{Provider: "ukvd", Kind: fueldata.ErrNoCredit}, exitNoCredit},
+		{"invalid postcode", fmt.Errorf("looking up: %w", fueldata.ErrInvalidPostcode), exitInvalidPostcode},
+		{"no stations", fueldata.ErrNoStations, exitNoStations},
+		{"malformed response", fmt.Errorf("decoding: %w", fueldata.ErrMalformedResponse), exitMalformedResponse},
+		{"station not found", &fueldata.StationMatchError{Kind: fueldata.ErrStationNotFound}, exitStationMatch},
+		{"ambiguous station", &fueldata.StationMatchError{Kind: fueldata.ErrAmbiguousStation}, exitStationMatch},
+		{"no price", fmt.Errorf("writing: %w", fueldata.ErrNoPrice), exitNoPrice},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("%s: exitCode(%v) = %d, want %d", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
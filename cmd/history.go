@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/poolski/fueltracker/fueldata"
+	"github.com/poolski/fueltracker/history"
+	"github.com/poolski/fueltracker/output"
+	"github.com/spf13/cobra"
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show statistics for the prices seen so far",
+	Long: `history -s "tesco extra" -f diesel --since 90d
+
+Shows the minimum, maximum, mean and median price, the change over the last
+week and a sparkline of the daily price, from the prices recorded by lookup
+and write. Use --group-by to compare stations, brands, areas or months.`,
+	RunE: doHistory,
+}
+
+func doHistory(cmd *cobra.Command, args []string) error {
+	fuel, err := fuelFlag(cmd)
+	if err != nil {
+		return err
+	}
+	if fuel == fueldata.FuelTypeEV {
+		return errors.New("EV charging has no price history")
+	}
+
+	outputFlag, _ := cmd.Flags().GetString("output")
+	format, err := output.ParseFormat(outputFlag)
+	if err != nil {
+		return err
+	}
+	groupFlag, _ := cmd.Flags().GetString("group-by")
+	groupBy, err := history.ParseGroupBy(groupFlag)
+	if err != nil {
+		return err
+	}
+	sinceFlag, _ := cmd.Flags().GetString("since")
+	since, err := history.ParseSince(sinceFlag, time.Now())
+	if err != nil {
+		return err
+	}
+	series, _ := cmd.Flags().GetBool("series")
+
+	// Only local files are read, so don't insist on a working provider.
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	registry, err := fueldata.RegistryFromConfig(cfg)
+	if err != nil {
+		return err
+	}
+	store, err := fueldata.HistoryFromConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	filter := history.Filter{Since: since}
+	if fuel != fueldata.FuelTypeAll {
+		filter.FuelType = fuel
+	}
+	filter.Brand, _ = cmd.Flags().GetString("brand")
+	if id, _ := cmd.Flags().GetString("station-id"); id != "" {
+		filter.StationIDs = append(filter.StationIDs, registry.AllIDs(id)...)
+	}
+	if name, _ := cmd.Flags().GetString("station"); name != "" {
+		filter.Station = name
+		// Also find the station under any names it used to have.
+		for _, id := range registry.IDsForName(name) {
+			filter.StationIDs = append(filter.StationIDs, registry.AllIDs(id)...)
+		}
+	}
+
+	entries, err := store.Entries()
+	if err != nil {
+		return err
+	}
+	groups := history.Summarize(filter.Apply(entries), groupBy)
+	if len(groups) == 0 && format.IsTable() {
+		fmt.Fprintf(cmd.OutOrStdout(), "No prices in %s match, prices are recorded by lookup and write\n", store.Path())
+		return nil
+	}
+	return output.RenderHistory(cmd.OutOrStdout(), format, groups, series)
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.Flags().StringP("station", "s", "", "only show stations with this in their name, now or in the past")
+	historyCmd.Flags().String("station-id", "", "only show the station with this ID")
+	historyCmd.Flags().String("brand", "", "only show stations of this brand, e.g. 'Tesco'")
+	historyCmd.Flags().String("since", "", "only show prices since then, e.g. '90d', '2w' or '2026-01-31'")
+	historyCmd.Flags().String("group-by", string(history.GroupByStation), "group prices by 'station', 'brand', 'outward' (postcode area) or 'month'")
+	historyCmd.Flags().Bool("series", false, "list every price as well as the statistics")
+	historyCmd.Flags().StringP("output", "o", string(output.Table), "output format: 'table', 'json', 'ndjson', 'csv' or 'yaml'")
+}
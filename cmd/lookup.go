@@ -1,42 +1,198 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
 	"github.com/poolski/fueltracker/fueldata"
+	"github.com/poolski/fueltracker/output"
+	"github.com/poolski/fueltracker/types"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
 // lookupCmd represents the lookup command
 var lookupCmd = &cobra.Command{
 	Use:   "lookup",
 	Short: "Look up fuel data for a postcode",
-	Long:  `lookup AB123XY`,
-	RunE:  doLookup,
+	Long: `lookup -p AB123XY
+
+Repeat --postcode, or use --postcode-file, to look up several postcodes at
+once. Stations found near more than one of them are only shown once.`,
+	RunE: doLookup,
 }
 
 func doLookup(cmd *cobra.Command, args []string) error {
-	c := fueldata.New(viper.GetString("ukvd_api_key"))
+	fuel, err := fuelFlag(cmd)
+	if err != nil {
+		return err
+	}
+
+	sortFlag, _ := cmd.Flags().GetString("sort")
+	sortBy, err := fueldata.ParseSortKey(sortFlag)
+	if err != nil {
+		return err
+	}
+	limit, _ := cmd.Flags().GetInt("limit")
+	renderer, isTable, err := outputFlags(cmd)
+	if err != nil {
+		return err
+	}
+	maxDistance, _ := cmd.Flags().GetFloat64("max-distance")
 
-	postcode, _ := cmd.Flags().GetString("postcode")
-	fuel, _ := cmd.Flags().GetString("fuel")
-	station, _ := cmd.Flags().GetString("station")
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
 
 	opts := fueldata.QueryOpts{
-		Postcode: postcode,
-		FuelType: fuel,
-		Location: station,
+		FuelType:    fuel,
+		MaxDistance: maxDistance,
 	}
+	var postcodes []string
+	if cmd.Flags().Changed("lat") || cmd.Flags().Changed("lon") {
+		if err := locationFlags(cmd, cfg, &opts); err != nil {
+			return err
+		}
+	} else {
+		if postcodes, err = postcodeFlags(cmd); err != nil {
+			return err
+		}
+		opts.Postcode = postcodes[0]
+	}
+	stationFlags(cmd, &opts)
 
-	records, err := c.GetFuelPrices(opts)
+	c, err := fueldata.FromConfig(cfg)
 	if err != nil {
 		return err
 	}
 
-	fueldata.PrintFuelPrices(records)
+	var results []*fueldata.PostcodeResult
+	var found []*types.SpecificFuelPrice
+	if len(postcodes) > 1 {
+		concurrency, _ := cmd.Flags().GetInt("concurrency")
+		batch, err := c.LookupMany(cmd.Context(), opts, postcodes, concurrency)
+		if err != nil {
+			return err
+		}
+		results, found = batch.Results, batch.Prices
+	} else {
+		res, err := c.Lookup(cmd.Context(), opts)
+		if err != nil {
+			return err
+		}
+		results = []*fueldata.PostcodeResult{{Postcode: opts.Postcode, Result: res}}
+		found = res.Prices
+	}
+
+	prices := fueldata.Rank(found, fueldata.RankOpts{
+		By:           sortBy,
+		Limit:        limit,
+		PencePerMile: cfg.ValuePencePerMile,
+	})
+
+	if err := renderer.Render(cmd.OutOrStdout(), prices); err != nil {
+		return err
+	}
+
+	// Keep stdout clean for other programs to read.
+	notes := cmd.OutOrStdout()
+	if !isTable {
+		notes = cmd.ErrOrStderr()
+	}
+	printSearchSummary(notes, results, opts, prices)
+	var ok []*fueldata.Result
+	for _, r := range results {
+		if r.Err == nil {
+			ok = append(ok, r.Result)
+		}
+	}
+	printCacheNote(notes, cfg, ok...)
 	return nil
 }
 
+// outputFlags returns the Renderer picked by the --output, --template and
+// --template-file flags, and whether it renders a table for people to read.
+func outputFlags(cmd *cobra.Command) (output.Renderer, bool, error) {
+	outputFlag, _ := cmd.Flags().GetString("output")
+	format, err := output.ParseFormat(outputFlag)
+	if err != nil {
+		return nil, false, err
+	}
+
+	text, _ := cmd.Flags().GetString("template")
+	if path, _ := cmd.Flags().GetString("template-file"); path != "" {
+		if text != "" {
+			return nil, false, errors.New("use either --template or --template-file, not both")
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, false, err
+		}
+		text = string(data)
+	}
+	if text == "" {
+		renderer, err := output.New(format)
+		return renderer, format.IsTable(), err
+	}
+
+	if cmd.Flags().Changed("output") {
+		return nil, false, errors.New("use either --output or a template, not both")
+	}
+	renderer, err := output.NewTemplate(text)
+	return renderer, false, err
+}
+
+// printSearchSummary reports how far the provider searched around each
+// postcode and how many of the stations it found are shown, as UKVD widens
+// the search in rural areas without saying so.
+func printSearchSummary(w io.Writer, results []*fueldata.PostcodeResult, opts fueldata.QueryOpts, shown []*types.SpecificFuelPrice) {
+	n := 0
+	for _, sp := range fueldata.ByStation(shown) {
+		if sp.StationID != "" {
+			n++
+		}
+	}
+
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Fprintf(w, "Couldn't search around %s: %v\n", r.Postcode, r.Err)
+			continue
+		}
+		// Overlapping feeds can list a station more than once.
+		found := map[string]bool{}
+		for _, stn := range r.Stations {
+			found[fueldata.StationID(stn)] = true
+		}
+		where := r.Postcode
+		if opts.Latitude != 0 || opts.Longitude != 0 {
+			where = fmt.Sprintf("%g,%g (near %s)", opts.Latitude, opts.Longitude, r.Postcode)
+		}
+		radius := "an unknown radius"
+		if r.SearchRadius > 0 {
+			radius = fmt.Sprintf("%g miles", r.SearchRadius)
+		}
+		line := fmt.Sprintf("Searched %s around %s with %s: %d stations found", radius, where, r.Provider, len(found))
+		if len(results) == 1 {
+			line += fmt.Sprintf(", %d shown", n)
+		}
+		fmt.Fprintln(w, line)
+	}
+	if len(results) > 1 {
+		fmt.Fprintf(w, "%d stations shown\n", n)
+	}
+}
+
 func init() {
 	rootCmd.AddCommand(lookupCmd)
-	lookupCmd.Flags().StringP("station", "s", "", "(optional) specific fuel station to show prices for")
+	addStationFlags(lookupCmd, "(optional) specific fuel station to show prices for")
+	lookupCmd.Flags().String("sort", "", "sort by 'price', 'distance', 'age' or 'value' (price weighed against distance)")
+	lookupCmd.Flags().IntP("limit", "n", 0, "only show the first N stations")
+	lookupCmd.Flags().StringP("output", "o", string(output.Table), "output format: 'table', 'json', 'ndjson', 'csv' or 'yaml'")
+	lookupCmd.Flags().String("template", "", "Go template to render the prices with, e.g. '{{range .}}{{.Station}}: {{.Price}} {{end}}'")
+	lookupCmd.Flags().String("template-file", "", "file containing a Go template to render the prices with")
+	lookupCmd.Flags().Float64("max-distance", 0, "only show stations within this many miles")
+	lookupCmd.Flags().String("postcode-file", "", "also look up the postcodes in this file, one per line, or '-' to read them from stdin")
+	lookupCmd.Flags().Int("concurrency", fueldata.DefaultConcurrency, "how many postcodes to look up at once")
 }
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// postcodesCmd represents the postcodes command
+var postcodesCmd = &cobra.Command{
+	Use:   "postcodes",
+	Short: "Manage the offline postcode directory",
+	Long:  `The postcode directory is used to find the nearest postcode to --lat and --lon without a network call`,
+}
+
+// postcodesImportCmd represents the postcodes import command
+var postcodesImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import a postcode directory from a CSV file",
+	Long: `Imports postcodes and their locations from a CSV file, such as the ONS Postcode
+Directory, replacing any imported before. Terminated postcodes and ones
+without a location are skipped.`,
+	Args: cobra.ExactArgs(1),
+	RunE: doPostcodesImport,
+}
+
+func doPostcodesImport(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	dir, err := postcodeDirectory(cfg)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	n, err := dir.Import(f)
+	if err != nil {
+		return fmt.Errorf("importing %s: %w", args[0], err)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Imported %d postcodes to %s\n", n, dir.Path)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(postcodesCmd)
+	postcodesCmd.AddCommand(postcodesImportCmd)
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/poolski/fueltracker/fueldata"
+	"github.com/spf13/cobra"
+)
+
+// recommendCmd represents the recommend command
+var recommendCmd = &cobra.Command{
+	Use:   "recommend",
+	Short: "Recommend the station that's cheapest once the drive there is paid for",
+	Long: `Works out what a fill-up costs at each station, including the fuel burnt
+driving there and back, and how much that saves over the nearest station.
+Set vehicle.mpg and vehicle.fill_litres in the config, or use --mpg and --litres.`,
+	RunE: doRecommend,
+}
+
+func doRecommend(cmd *cobra.Command, args []string) error {
+	fuel, err := fuelFlag(cmd)
+	if err != nil {
+		return err
+	}
+	if fuel == fueldata.FuelTypeAll || fuel == fueldata.FuelTypeEV {
+		return fmt.Errorf("can't recommend a station for fuel type %q, pick a single fuel", fuel)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	vehicle := fueldata.Vehicle{MPG: cfg.Vehicle.MPG, FillLitres: cfg.Vehicle.FillLitres}
+	if cmd.Flags().Changed("mpg") {
+		vehicle.MPG, _ = cmd.Flags().GetFloat64("mpg")
+	}
+	if cmd.Flags().Changed("litres") {
+		vehicle.FillLitres, _ = cmd.Flags().GetFloat64("litres")
+	}
+	if err := vehicle.Validate(); err != nil {
+		return fmt.Errorf("%w, set it in the config or with --mpg and --litres", err)
+	}
+
+	opts := fueldata.QueryOpts{
+		FuelType: fuel,
+	}
+	if err := locationFlags(cmd, cfg, &opts); err != nil {
+		return err
+	}
+	stationFlags(cmd, &opts)
+
+	c, err := fueldata.FromConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.Lookup(cmd.Context(), opts)
+	if err != nil {
+		return err
+	}
+
+	recs, err := fueldata.Recommend(res.Prices, vehicle)
+	if err != nil {
+		return err
+	}
+	fueldata.WriteRecommendations(cmd.OutOrStdout(), recs, vehicle)
+	printCacheNote(cmd.OutOrStdout(), cfg, res)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(recommendCmd)
+	addStationFlags(recommendCmd, "(optional) only consider stations matching this name")
+	recommendCmd.Flags().Float64("mpg", 0, "fuel economy in miles per imperial gallon, overriding vehicle.mpg")
+	recommendCmd.Flags().Float64("litres", 0, "litres bought per fill-up, overriding vehicle.fill_litres")
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/poolski/fueltracker/fueldata"
+	"github.com/spf13/cobra"
+)
+
+// reconcileCmd represents the reconcile command
+var reconcileCmd = &cobra.Command{
+	Use:   "reconcile",
+	Short: "Compare prices between two providers",
+	Long:  `Queries two providers for the same postcode and reports the stations whose prices disagree, so you can tell which feed is stale`,
+	RunE:  doReconcile,
+}
+
+func doReconcile(cmd *cobra.Command, args []string) error {
+	fuel, err := fuelFlag(cmd)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	opts := fueldata.QueryOpts{
+		FuelType: fuel,
+	}
+	if err := locationFlags(cmd, cfg, &opts); err != nil {
+		return err
+	}
+
+	threshold, _ := cmd.Flags().GetFloat64("threshold")
+	names, _ := cmd.Flags().GetStringSlice("providers")
+
+	// Default to comparing the primary provider with its first fallback.
+	if len(names) == 0 {
+		if len(cfg.FallbackProviders) == 0 {
+			return errors.New("specify two providers with --providers or configure fallback_providers")
+		}
+		names = []string{cfg.Provider, cfg.FallbackProviders[0]}
+	}
+	if len(names) != 2 {
+		return errors.New("reconcile compares exactly two providers")
+	}
+
+	a, err := fueldata.NewProvider(names[0], cfg)
+	if err != nil {
+		return err
+	}
+	b, err := fueldata.NewProvider(names[1], cfg)
+	if err != nil {
+		return err
+	}
+
+	ds, err := fueldata.Reconcile(cmd.Context(), opts, a, b, threshold)
+	if err != nil {
+		return err
+	}
+
+	fueldata.WriteDiscrepancies(cmd.OutOrStdout(), ds)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(reconcileCmd)
+	reconcileCmd.Flags().StringSlice("providers", nil, "the two providers to compare, e.g. 'ukvd,retail'")
+	reconcileCmd.Flags().Float64("threshold", 1, "report prices which differ by more than this many pence")
+}
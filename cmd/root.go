@@ -1,11 +1,22 @@
 package cmd
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/poolski/fueltracker/config"
+	"github.com/poolski/fueltracker/fueldata"
+	"github.com/poolski/fueltracker/postcode"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"golang.org/x/exp/slog"
@@ -31,9 +42,13 @@ var rootCmd = &cobra.Command{
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	err := rootCmd.Execute()
+	// Cancel in-flight requests if we're interrupted or stopped by systemd.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err := rootCmd.ExecuteContext(ctx)
 	if err != nil {
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
 
@@ -51,11 +66,26 @@ func init() {
 	confDir := homeDir + sep + ".config"
 	rootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", confDir+"/fueltracker/config.json", "config file")
 
-	rootCmd.PersistentFlags().StringP("postcode", "p", "", "postcode to look up fuel prices for e.g. 'AB123XY'")
+	rootCmd.PersistentFlags().StringSliceP("postcode", "p", nil, "postcode to look up fuel prices for e.g. 'AB123XY'")
 	if err := rootCmd.MarkPersistentFlagRequired("postcode"); err != nil {
 		log.Fatal(err)
 	}
-	rootCmd.PersistentFlags().StringP("fuel", "f", "unleaded", "(optional) specific fuel type to show prices for")
+	rootCmd.PersistentFlags().Float64("lat", 0, "latitude to look up fuel prices near, instead of --postcode")
+	rootCmd.PersistentFlags().Float64("lon", 0, "longitude to look up fuel prices near, instead of --postcode")
+	rootCmd.PersistentFlags().StringP("fuel", "f", "unleaded", "(optional) specific fuel type to show prices for, e.g. 'diesel', 'e10' or 'all'")
+
+	rootCmd.PersistentFlags().Bool("no-cache", false, "don't read or write cached API responses")
+	rootCmd.PersistentFlags().Bool("refresh", false, "ignore cached API responses and fetch new ones")
+	rootCmd.PersistentFlags().Bool("offline", false, "don't use the network, serve the last cached API response")
+	for key, flag := range map[string]string{
+		"cache.disabled": "no-cache",
+		"cache.refresh":  "refresh",
+		"cache.offline":  "offline",
+	} {
+		if err := viper.BindPFlag(key, rootCmd.PersistentFlags().Lookup(flag)); err != nil {
+			log.Fatal(err)
+		}
+	}
 
 	// The `generate` command doesn't need the postcode flag
 	if err := configCmd.InheritedFlags().SetAnnotation("postcode", cobra.BashCompOneRequiredFlag, []string{"false"}); err != nil {
@@ -68,8 +98,199 @@ func initConfig() {
 	viper.SetConfigFile(cfgFile)
 	viper.AutomaticEnv() // read in environment variables that match
 
+	// Register the keys so they can be set from the environment alone.
+	viper.SetDefault("provider", fueldata.DefaultProvider)
+	viper.SetDefault("ukvd_api_key", "")
+	viper.SetDefault("snitch_api_key", "")
+	viper.SetDefault("snitch_id", "")
+
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err != nil {
 		configCmd.Root()
 	}
 }
+
+// loadConfig unmarshals the active configuration.
+func loadConfig() (*config.Config, error) {
+	cfg := &config.Config{}
+	if err := viper.Unmarshal(cfg); err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+	return cfg, nil
+}
+
+// fuelFlag returns the canonical fuel type for the --fuel flag, so typos are
+// caught before we make any API calls.
+func fuelFlag(cmd *cobra.Command) (string, error) {
+	fuel, _ := cmd.Flags().GetString("fuel")
+	return fueldata.ParseFuelType(fuel)
+}
+
+// postcodeFlags returns the postcodes given with --postcode, and in the
+// --postcode-file for commands which have one, in the standard format and
+// without duplicates. There is always at least one.
+func postcodeFlags(cmd *cobra.Command) ([]string, error) {
+	raw, _ := cmd.Flags().GetStringSlice("postcode")
+	if path, _ := cmd.Flags().GetString("postcode-file"); path != "" {
+		fromFile, err := readPostcodeFile(cmd, path)
+		if err != nil {
+			return nil, err
+		}
+		raw = append(raw, fromFile...)
+	}
+	if len(raw) == 0 {
+		_, err := postcode.Normalize("")
+		return nil, err
+	}
+
+	var pcs []string
+	seen := map[string]bool{}
+	for _, s := range raw {
+		pc, err := postcode.Normalize(s)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[pc] {
+			seen[pc] = true
+			pcs = append(pcs, pc)
+		}
+	}
+	return pcs, nil
+}
+
+// readPostcodeFile reads one postcode per line from path, or from stdin if
+// path is "-". Blank lines and lines starting with # are skipped.
+func readPostcodeFile(cmd *cobra.Command, path string) ([]string, error) {
+	var r io.Reader = cmd.InOrStdin()
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var pcs []string
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		pcs = append(pcs, line)
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("reading postcodes from %s: %w", path, err)
+	}
+	return pcs, nil
+}
+
+// maxPostcodeDistance is how far, in miles, --lat and --lon can be from the
+// nearest postcode before we decide they aren't in the UK.
+const maxPostcodeDistance = 25
+
+// locationFlags fills in where to search from, in opts, from either the
+// --postcode flag or the --lat and --lon flags. Coordinates are resolved to
+// the nearest postcode using the imported postcode directory, for providers
+// which only search by postcode. Either way, mistakes are caught before they
+// cost an API call.
+func locationFlags(cmd *cobra.Command, cfg *config.Config, opts *fueldata.QueryOpts) error {
+	flags := cmd.Flags()
+	if !flags.Changed("lat") && !flags.Changed("lon") {
+		pcs, err := postcodeFlags(cmd)
+		if err != nil {
+			return err
+		}
+		if len(pcs) > 1 {
+			return fmt.Errorf("%s only takes one postcode", cmd.Name())
+		}
+		opts.Postcode = pcs[0]
+		return nil
+	}
+
+	if !flags.Changed("lat") || !flags.Changed("lon") {
+		return errors.New("--lat and --lon must be used together")
+	}
+	if flags.Changed("postcode") || flags.Changed("postcode-file") {
+		return errors.New("use either --postcode or --lat and --lon, not both")
+	}
+	lat, _ := flags.GetFloat64("lat")
+	lon, _ := flags.GetFloat64("lon")
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return fmt.Errorf("%g,%g aren't valid coordinates", lat, lon)
+	}
+
+	dir, err := postcodeDirectory(cfg)
+	if err != nil {
+		return err
+	}
+	nearest, dist, err := dir.Nearest(lat, lon)
+	if err != nil {
+		return err
+	}
+	if dist > maxPostcodeDistance {
+		return fmt.Errorf("%w: no postcode within %d miles of %g,%g, are they in the UK?", postcode.ErrInvalid, maxPostcodeDistance, lat, lon)
+	}
+	pc, err := postcode.Normalize(nearest.Postcode)
+	if err != nil {
+		return err
+	}
+
+	opts.Postcode = pc
+	opts.Latitude = lat
+	opts.Longitude = lon
+	return nil
+}
+
+// postcodeDirectory returns the configured postcode directory.
+func postcodeDirectory(cfg *config.Config) (*postcode.Directory, error) {
+	path := cfg.PostcodesFile
+	if path == "" {
+		var err error
+		if path, err = postcode.DefaultDirectoryPath(); err != nil {
+			return nil, err
+		}
+	}
+	return &postcode.Directory{Path: path}, nil
+}
+
+// addStationFlags adds the flags used to pick out particular stations.
+func addStationFlags(cmd *cobra.Command, usage string) {
+	cmd.Flags().StringP("station", "s", "", usage+", matched on any part of its name")
+	cmd.Flags().String("station-id", "", "station ID, as shown when a station can't be matched")
+	cmd.Flags().String("brand", "", "only show stations of this brand, e.g. 'Tesco'")
+	cmd.Flags().String("station-postcode", "", "only show the station at this postcode")
+	cmd.Flags().StringSlice("has", nil, "only show stations with all of these amenities, e.g. 'tyre-pump,car-wash'")
+}
+
+// stationFlags fills in the station selectors in opts from the flags added
+// by addStationFlags.
+func stationFlags(cmd *cobra.Command, opts *fueldata.QueryOpts) {
+	opts.Location, _ = cmd.Flags().GetString("station")
+	opts.StationID, _ = cmd.Flags().GetString("station-id")
+	opts.Brand, _ = cmd.Flags().GetString("brand")
+	opts.StationPostcode, _ = cmd.Flags().GetString("station-postcode")
+	opts.Amenities, _ = cmd.Flags().GetStringSlice("has")
+}
+
+// printCacheNote says if any of the results came from the cache, and how
+// old the oldest of them is.
+func printCacheNote(w io.Writer, cfg *config.Config, results ...*fueldata.Result) {
+	var oldest *fueldata.Result
+	for _, r := range results {
+		if r.FromCache && (oldest == nil || r.FetchedAt.Before(oldest.FetchedAt)) {
+			oldest = r
+		}
+	}
+	if oldest == nil {
+		return
+	}
+	fmt.Fprintf(w, "Cached prices from %s (%s old)",
+		oldest.FetchedAt.Local().Format("02/01/2006 15:04"), oldest.Age().Round(time.Minute))
+	if cfg.Cache.Offline {
+		fmt.Fprintln(w, ", as we're offline")
+		return
+	}
+	fmt.Fprintln(w, ", use --refresh to fetch new ones")
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/poolski/fueltracker/fueldata"
+	"github.com/spf13/cobra"
+)
+
+// stationsCmd represents the stations command
+var stationsCmd = &cobra.Command{
+	Use:   "stations",
+	Short: "List the stations seen so far",
+	Long:  `Lists every station seen by lookup and write, with its ID and any names it used to have`,
+	RunE:  doStations,
+}
+
+func doStations(cmd *cobra.Command, args []string) error {
+	// Only the registry is needed, so don't insist on a working provider.
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	registry, err := fueldata.RegistryFromConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	table := tablewriter.NewWriter(cmd.OutOrStdout())
+	table.SetHeader([]string{"ID", "Name", "Brand", "Postcode", "Last Seen", "Previous Names"})
+	for _, known := range registry.List() {
+		cur := known.Current()
+		var previous []string
+		for _, n := range known.Names[:len(known.Names)-1] {
+			previous = append(previous, n.Name)
+		}
+		table.Append([]string{
+			known.ID,
+			cur.Name,
+			cur.Brand,
+			known.Postcode,
+			cur.LastSeen.Local().Format("02/01/2006"),
+			strings.Join(previous, ", "),
+		})
+	}
+	table.Render()
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(stationsCmd)
+}
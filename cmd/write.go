@@ -8,6 +8,7 @@ import (
 	"github.com/PremiereGlobal/go-deadmanssnitch"
 	"github.com/poolski/fueltracker/config"
 	"github.com/poolski/fueltracker/fueldata"
+	"github.com/poolski/fueltracker/output"
 	"github.com/poolski/fueltracker/sheets"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -22,58 +23,101 @@ var writeCmd = &cobra.Command{
 }
 
 func doWrite(cmd *cobra.Command, args []string) error {
-	postcode, _ := cmd.Flags().GetString("postcode")
-	fuel, _ := cmd.Flags().GetString("fuel")
-	station, err := cmd.Flags().GetString("station")
+	fuel, err := fuelFlag(cmd)
 	if err != nil {
-		return errors.New("station flag required but not set")
+		return err
+	}
+	if fuel == fueldata.FuelTypeEV {
+		return errors.New("EV charging has no price to write, use lookup to list charging stations")
+	}
+	changes, _ := cmd.Flags().GetBool("changes")
+	format, err := changesOutputFlag(cmd)
+	if err != nil {
+		return err
 	}
 
-	log.Printf("fetching %s fuel prices for %s...", fuel, station)
+	// Only ever write one station's prices, so an unclear --station fails
+	// rather than writing the wrong row.
+	opts := fueldata.QueryOpts{
+		FuelType: fuel,
+		Unique:   true,
+	}
+	stationFlags(cmd, &opts)
+	q := opts.StationQuery()
+	if q.IsZero() {
+		return errors.New("pick a station with --station, --station-id or --brand and --station-postcode")
+	}
 
-	cfg := &config.GoogleConfig{
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if err := locationFlags(cmd, cfg, &opts); err != nil {
+		return err
+	}
+
+	log.Printf("fetching %s fuel prices for %s...", fuel, q)
+
+	sheetsCfg := &config.GoogleConfig{
 		CredentialsPath: viper.GetString("google.credentials_path"),
 		SpreadsheetID:   viper.GetString("google.spreadsheet_id"),
 		WorksheetRange:  viper.GetString("google.worksheet_range"),
 	}
 
-	opts := fueldata.QueryOpts{
-		Postcode: postcode,
-		FuelType: fuel,
-		Location: station,
-	}
-
-	sheets, err := sheets.New(cfg)
+	sheets, err := sheets.New(sheetsCfg)
 	if err != nil {
 		return fmt.Errorf("creating google sheets connection: %w", err)
 	}
 
-	c := fueldata.New(viper.GetString("ukvd_api_key"))
+	c, err := fueldata.FromConfig(cfg)
+	if err != nil {
+		return err
+	}
 
-	records, err := c.GetFuelPrices(opts)
+	res, err := c.Lookup(cmd.Context(), opts)
 	if err != nil {
 		return fmt.Errorf("getting fuel prices: %w", err)
 	}
+	records := res.Prices
+	printCacheNote(cmd.ErrOrStderr(), cfg, res)
 
-	if err := sheets.Write(records[0]); err != nil {
-		return err
-	} else {
-		// If you don't have a Dead Man's Snitch account, we won't do this.
-		if c.SnitchAPIKey != "" {
-			dms := deadmanssnitch.NewClient(c.SnitchAPIKey)
-			if err := dms.CheckIn(viper.GetString("snitch_id")); err != nil {
-				log.Printf("writing to DMS: %v", err)
-			}
+	// In all-fuels mode, record every fuel the station sells.
+	if fuel != fueldata.FuelTypeAll {
+		records = records[:1]
+	}
+	// A station with no prices comes back as a placeholder, which
+	// mustn't be written or checked in as if it were a price.
+	for _, r := range records {
+		if r.StationID == "" || r.Station == "" {
+			return fmt.Errorf("%s: %w for %s", q, fueldata.ErrNoPrice, fuel)
 		}
-		log.Println("successfully written latest price to Google Sheets")
-		return nil
 	}
+
+	for _, r := range records {
+		if err := sheets.Write(r); err != nil {
+			return err
+		}
+	}
+
+	// If you don't have a Dead Man's Snitch account, we won't do this.
+	if c.SnitchAPIKey != "" {
+		dms := deadmanssnitch.NewClient(c.SnitchAPIKey)
+		if err := dms.CheckIn(viper.GetString("snitch_id")); err != nil {
+			log.Printf("writing to DMS: %v", err)
+		}
+	}
+	log.Println("successfully written latest price to Google Sheets")
+
+	if changes {
+		return printChanges(cmd.OutOrStdout(), cmd.ErrOrStderr(), format, res, compareFlags(cmd, cfg, fuel))
+	}
+	return nil
 }
 
 func init() {
 	rootCmd.AddCommand(writeCmd)
-	writeCmd.Flags().StringP("station", "s", "", "specific fuel station to show prices for")
-	if err := writeCmd.MarkFlagRequired("station"); err != nil {
-		log.Fatal(err)
-	}
+	addStationFlags(writeCmd, "specific fuel station to write prices for")
+	addCompareFlags(writeCmd)
+	writeCmd.Flags().Bool("changes", false, "also show what has changed around the postcode since the last run")
+	writeCmd.Flags().StringP("output", "o", string(output.Table), "output format for --changes: 'table', 'json', 'ndjson', 'csv' or 'yaml'")
 }
@@ -1,14 +1,78 @@
 package config
 
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
 type GoogleConfig struct {
 	CredentialsPath string `mapstructure:"credentials_path"`
 	SpreadsheetID   string `mapstructure:"spreadsheet_id"`
 	WorksheetRange  string `mapstructure:"worksheet_range"`
 }
 
+type RetailConfig struct {
+	Feeds       []string `mapstructure:"feeds"`
+	RadiusMiles int      `mapstructure:"radius_miles"`
+	GeocoderURL string   `mapstructure:"geocoder_url"`
+}
+
+type CacheConfig struct {
+	Dir      string        `mapstructure:"dir"`
+	TTL      time.Duration `mapstructure:"ttl"`
+	Disabled bool          `mapstructure:"disabled"`
+	Refresh  bool          `mapstructure:"refresh"`
+	Offline  bool          `mapstructure:"offline"`
+}
+
+type HTTPConfig struct {
+	Timeout       time.Duration `mapstructure:"timeout"`
+	Retries       int           `mapstructure:"retries"`
+	RetryDelay    time.Duration `mapstructure:"retry_delay"`
+	MaxRetryDelay time.Duration `mapstructure:"max_retry_delay"`
+}
+
+type VehicleConfig struct {
+	MPG        float64 `mapstructure:"mpg"`
+	FillLitres float64 `mapstructure:"fill_litres"`
+}
+
+type ChangesConfig struct {
+	MinRisePence float64 `mapstructure:"min_rise_pence"`
+	MinDropPence float64 `mapstructure:"min_drop_pence"`
+}
+
 type Config struct {
-	UKVDAPIKey   string       `mapstructure:"ukvd_api_key"`
-	SnitchAPIKey string       `mapstructure:"snitch_api_key"`
-	SnitchID     string       `mapstructure:"snitch_id"`
-	Google       GoogleConfig `mapstructure:"google"`
+	Provider          string        `mapstructure:"provider"`
+	FallbackProviders []string      `mapstructure:"fallback_providers"`
+	UKVDAPIKey        string        `mapstructure:"ukvd_api_key"`
+	SnitchAPIKey      string        `mapstructure:"snitch_api_key"`
+	SnitchID          string        `mapstructure:"snitch_id"`
+	PostcodesFile     string        `mapstructure:"postcodes_file"`
+	HistoryFile       string        `mapstructure:"history_file"`
+	StationsFile      string        `mapstructure:"stations_file"`
+	ValuePencePerMile float64       `mapstructure:"value_pence_per_mile"`
+	Google            GoogleConfig  `mapstructure:"google"`
+	Retail            RetailConfig  `mapstructure:"retail"`
+	Cache             CacheConfig   `mapstructure:"cache"`
+	HTTP              HTTPConfig    `mapstructure:"http"`
+	Vehicle           VehicleConfig `mapstructure:"vehicle"`
+	Changes           ChangesConfig `mapstructure:"changes"`
+}
+
+// DataDir returns the directory fueltracker keeps its data in, such as the
+// postcode directory. It follows the XDG base directory spec, so it is
+// $XDG_DATA_HOME/fueltracker, or ~/.local/share/fueltracker if that isn't
+// set.
+func DataDir() (string, error) {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "fueltracker"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not find user's home directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "share", "fueltracker"), nil
 }
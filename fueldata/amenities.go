@@ -0,0 +1,120 @@
+package fueldata
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/poolski/fueltracker/types"
+)
+
+// Amenity is a service a station may offer besides fuel.
+type Amenity struct {
+	Name    string
+	Aliases []string
+	// Label is the short form shown in tables.
+	Label string
+	Has   func(stn types.FuelStation) bool
+}
+
+// Amenities is the registry of amenities, in display order.
+var Amenities = []Amenity{
+	{
+		Name:    "tyre-pump",
+		Aliases: []string{"air", "tyres", "tire-pump", "air-pump"},
+		Label:   "Air",
+		Has:     func(stn types.FuelStation) bool { return stn.Features.Services.HasTyrePump },
+	},
+	{
+		Name:    "car-wash",
+		Aliases: []string{"wash", "carwash"},
+		Label:   "Wash",
+		Has:     func(stn types.FuelStation) bool { return stn.Features.Services.HasCarWash },
+	},
+	{
+		Name:    "water",
+		Aliases: []string{"screenwash"},
+		Label:   "Water",
+		Has:     func(stn types.FuelStation) bool { return stn.Features.Services.HasWater },
+	},
+	{
+		Name:    "cash-point",
+		Aliases: []string{"cash", "atm", "cashpoint", "cash-machine"},
+		Label:   "ATM",
+		Has:     func(stn types.FuelStation) bool { return stn.Features.Services.HasCashPoint },
+	},
+	{
+		Name:    "vacuum",
+		Aliases: []string{"vac", "car-vacuum", "hoover"},
+		Label:   "Vac",
+		Has:     func(stn types.FuelStation) bool { return stn.Features.Services.HasCarVacuum },
+	},
+}
+
+// amenityIndex maps every normalized name and alias onto an amenity.
+var amenityIndex = map[string]*Amenity{}
+
+func init() {
+	for i := range Amenities {
+		a := &Amenities[i]
+		amenityIndex[fuelTypeKey(a.Name)] = a
+		for _, alias := range a.Aliases {
+			amenityIndex[fuelTypeKey(alias)] = a
+		}
+	}
+}
+
+// ParseAmenity returns the canonical name for s, or an error listing the
+// valid amenities if it isn't one we know.
+func ParseAmenity(s string) (string, error) {
+	a, ok := amenityIndex[fuelTypeKey(s)]
+	if !ok {
+		return "", fmt.Errorf("unknown amenity %q, valid amenities are: %s", s, strings.Join(ValidAmenities(), ", "))
+	}
+	return a.Name, nil
+}
+
+// ValidAmenities describes each amenity and its aliases, for help and error
+// messages.
+func ValidAmenities() []string {
+	out := make([]string, 0, len(Amenities))
+	for _, a := range Amenities {
+		out = append(out, a.Name+" ("+strings.Join(a.Aliases, "/")+")")
+	}
+	return out
+}
+
+// hasAmenities reports whether stn offers every amenity in names, which
+// must be canonical.
+func hasAmenities(stn types.FuelStation, names []string) bool {
+	for _, name := range names {
+		a, ok := amenityIndex[fuelTypeKey(name)]
+		if !ok || !a.Has(stn) {
+			return false
+		}
+	}
+	return true
+}
+
+// amenityLabels returns the labels of the amenities f includes.
+func amenityLabels(f types.StationFeatures) string {
+	stn := types.FuelStation{Features: f}
+	var labels []string
+	for _, a := range Amenities {
+		if a.Has(stn) {
+			labels = append(labels, a.Label)
+		}
+	}
+	return strings.Join(labels, " ")
+}
+
+// AmenityNames returns the names of the amenities f includes.
+func AmenityNames(f types.StationFeatures) []string {
+	stn := types.FuelStation{Features: f}
+	var names []string
+	for _, a := range Amenities {
+		if a.Has(stn) {
+			names = append(names, a.Name)
+		}
+	}
+	return names
+}
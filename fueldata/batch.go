@@ -0,0 +1,123 @@
+package fueldata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/poolski/fueltracker/types"
+)
+
+// DefaultConcurrency is how many lookups LookupMany runs at once if it
+// isn't told otherwise.
+const DefaultConcurrency = 4
+
+// PostcodeResult is the outcome of the lookup for one postcode in a batch.
+// Err is set if that lookup failed, in which case Result is nil.
+type PostcodeResult struct {
+	Postcode string
+	*Result
+	Err error
+}
+
+// BatchResult is the outcome of LookupMany.
+type BatchResult struct {
+	// Results has one entry per postcode, in the order they were given.
+	Results []*PostcodeResult
+	// Prices is the prices from every successful lookup, merged with
+	// MergePrices.
+	Prices []*types.SpecificFuelPrice
+}
+
+// LookupMany runs a Lookup with opts for each of postcodes, at most
+// concurrency at a time, and merges the results. A lookup failing doesn't
+// stop the others; its error is recorded in its PostcodeResult. An error is
+// only returned if every lookup failed, or ctx was cancelled.
+func (c *FuelData) LookupMany(ctx context.Context, opts QueryOpts, postcodes []string, concurrency int) (*BatchResult, error) {
+	if len(postcodes) == 0 {
+		return nil, errors.New("no postcodes to look up")
+	}
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	results := make([]*PostcodeResult, len(postcodes))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency && w < len(postcodes); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				o := opts
+				o.Postcode = postcodes[i]
+				res, err := c.Lookup(ctx, o)
+				results[i] = &PostcodeResult{Postcode: postcodes[i], Result: res, Err: err}
+			}
+		}()
+	}
+
+dispatch:
+	for i := range postcodes {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	batch := &BatchResult{Results: results}
+	var errs []error
+	var all [][]*types.SpecificFuelPrice
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.Postcode, r.Err))
+			continue
+		}
+		all = append(all, r.Prices)
+	}
+	if len(all) == 0 {
+		return nil, errors.Join(errs...)
+	}
+	batch.Prices = MergePrices(all...)
+	return batch, nil
+}
+
+// MergePrices combines the prices from several lookups, such as around
+// postcodes whose search areas overlap, so each station's price for each
+// fuel appears once. Where a station was found more than once, the record
+// with the shortest distance is kept. Stations are kept in the order they
+// were first seen.
+func MergePrices(lists ...[]*types.SpecificFuelPrice) []*types.SpecificFuelPrice {
+	var out []*types.SpecificFuelPrice
+	index := map[string]int{}
+	for _, list := range lists {
+		for _, r := range list {
+			// Skip placeholders for lookups which found nothing.
+			if r.StationID == "" {
+				continue
+			}
+			key := r.StationID + "|" + r.FuelType
+			i, ok := index[key]
+			if !ok {
+				index[key] = len(out)
+				out = append(out, r)
+				continue
+			}
+			if r.Distance < out[i].Distance {
+				out[i] = r
+			}
+		}
+	}
+	if len(out) == 0 {
+		out = append(out, nothingFound())
+	}
+	return out
+}
@@ -0,0 +1,51 @@
+package fueldata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Chain is a Provider which tries each of its providers in order and returns
+// the first successful response. Providers built by ProviderFromConfig all
+// share the cache settings, so in offline mode each one serves its cached
+// responses and falling back never goes to the network.
+type Chain struct {
+	Providers []Provider
+}
+
+func NewChain(providers ...Provider) *Chain {
+	return &Chain{Providers: providers}
+}
+
+func (c *Chain) Name() string {
+	names := make([]string, 0, len(c.Providers))
+	for _, p := range c.Providers {
+		names = append(names, p.Name())
+	}
+	return strings.Join(names, ",")
+}
+
+func (c *Chain) Stations(ctx context.Context, opts QueryOpts) (*Response, error) {
+	var errs []error
+	for i, p := range c.Providers {
+		res, err := p.Stations(ctx, opts)
+		if err == nil {
+			return res, nil
+		}
+		// Don't try the next provider if we've been cancelled.
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+		if i < len(c.Providers)-1 {
+			log.Printf("provider %s failed, falling back to %s: %v", p.Name(), c.Providers[i+1].Name(), err)
+		}
+	}
+	if len(errs) == 0 {
+		return nil, errors.New("no providers configured")
+	}
+	return nil, fmt.Errorf("all providers failed: %w", errors.Join(errs...))
+}
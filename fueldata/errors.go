@@ -0,0 +1,83 @@
+package fueldata
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/poolski/fueltracker/postcode"
+)
+
+// Errors returned by providers, which can be checked with errors.Is.
+var (
+	ErrInvalidAPIKey     = errors.New("invalid API key")
+	ErrNoCredit          = errors.New("out of API credit")
+	ErrInvalidPostcode   = postcode.ErrInvalid
+	ErrNoStations        = errors.New("no fuel stations found")
+	ErrMalformedResponse = errors.New("malformed API response")
+	ErrNoPrice           = errors.New("no price reported")
+)
+
+// ukvdStatusErrors maps the status codes UKVD reports, both for the request
+// as a whole and for the lookup itself, onto our errors.
+var ukvdStatusErrors = map[string]error{
+	"KeyInvalid":           ErrInvalidAPIKey,
+	"KeyExpired":           ErrInvalidAPIKey,
+	"KeyDisabled":          ErrInvalidAPIKey,
+	"AccountInactive":      ErrInvalidAPIKey,
+	"Unauthorised":         ErrInvalidAPIKey,
+	"InsufficientCredit":   ErrNoCredit,
+	"CreditExhausted":      ErrNoCredit,
+	"DailyLimitExceeded":   ErrNoCredit,
+	"MonthlyLimitExceeded": ErrNoCredit,
+	"ItemLimitExceeded":    ErrNoCredit,
+	"InvalidSearchTerm":    ErrInvalidPostcode,
+	"SearchTermInvalid":    ErrInvalidPostcode,
+	"InvalidPostcode":      ErrInvalidPostcode,
+	"NoResultsFound":       ErrNoStations,
+	"NoMatchFound":         ErrNoStations,
+}
+
+// APIError is a failure reported by a provider's API. It matches one of the
+// Err values above with errors.Is when the status code is recognised.
+type APIError struct {
+	Provider      string
+	StatusCode    string
+	StatusMessage string
+	Kind          error
+}
+
+func (e *APIError) Error() string {
+	msg := e.StatusMessage
+	if msg == "" {
+		msg = e.StatusCode
+	}
+	if e.Kind != nil {
+		return fmt.Sprintf("%s: %v: %s", e.Provider, e.Kind, msg)
+	}
+	return fmt.Sprintf("%s: %s", e.Provider, msg)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Kind
+}
+
+// classifyUKVDStatus returns the error for a UKVD status code. Codes we don't
+// know are classified by keyword, as UKVD adds new ones from time to time.
+func classifyUKVDStatus(code string) error {
+	if err, ok := ukvdStatusErrors[code]; ok {
+		return err
+	}
+	lower := strings.ToLower(code)
+	switch {
+	case strings.Contains(lower, "credit") || strings.Contains(lower, "limit"):
+		return ErrNoCredit
+	case strings.HasPrefix(lower, "key") || strings.Contains(lower, "auth"):
+		return ErrInvalidAPIKey
+	case strings.Contains(lower, "searchterm") || strings.Contains(lower, "postcode"):
+		return ErrInvalidPostcode
+	case strings.Contains(lower, "noresult") || strings.Contains(lower, "nomatch"):
+		return ErrNoStations
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package fueldata
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestClassifyUKVDStatus(t *testing.T) {
+	tests := []struct {
+		code string
+		want error
+	}{
+		{"KeyInvalid", ErrInvalidAPIKey},
+		{"Unauthorised", ErrInvalidAPIKey},
+		{"InsufficientCredit", ErrNoCredit},
+		{"DailyLimitExceeded", ErrNoCredit},
+		{"InvalidPostcode", ErrInvalidPostcode},
+		{"NoResultsFound", ErrNoStations},
+		// Codes we haven't seen are classified by keyword.
+		{"KeyRevoked", ErrInvalidAPIKey},
+		{"NotAuthorised", ErrInvalidAPIKey},
+		{"HourlyLimitExceeded", ErrNoCredit},
+		{"PostcodeNotRecognised", ErrInvalidPostcode},
+		{"NoMatchForSearch", ErrNoStations},
+		{"Success", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := classifyUKVDStatus(tt.code); got != tt.want {
+			t.Errorf("classifyUKVDStatus(%q) = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	err := error(&APIError{Provider: "ukvd", StatusCode: "KeyInvalid", Kind: ErrInvalidAPIKey})
+	if !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("errors.Is(%v, ErrInvalidAPIKey) = false", err)
+	}
+	if want := "ukvd: invalid API key: KeyInvalid"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err, want)
+	}
+}
@@ -1,180 +1,657 @@
 package fueldata
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"log"
-	"net/http"
-	"net/url"
 	"os"
-	"strings"
 	"time"
 
 	"github.com/olekukonko/tablewriter"
+	"github.com/poolski/fueltracker/geo"
+	"github.com/poolski/fueltracker/history"
+	"github.com/poolski/fueltracker/postcode"
 	"github.com/poolski/fueltracker/types"
 	"github.com/spf13/viper"
-	"golang.org/x/text/cases"
-	"golang.org/x/text/language"
-)
-
-const fuelPriceEndpoint = "api/datapackage/FuelPriceData"
-const (
-	FuelTypeUnleaded      = "Unleaded"
-	FuelTypeSuperUnleaded = "Super Unleaded"
-	FuelTypeDiesel        = "Diesel"
-	FuelTypePremiumDiesel = "Premium Diesel"
 )
 
 type FuelData struct {
-	APIKey       string
-	BaseURL      string
+	Provider     Provider
 	SnitchAPIKey string
-	titleCaser   cases.Caser
+	// Registry, if set, records every station seen and lets stations be
+	// found by names they used to have.
+	Registry *Registry
+	// History, if set, records every price seen.
+	History *history.Store
+	// Snapshots, if set, keeps what each lookup found so the next lookup of
+	// the same area can report what changed.
+	Snapshots *history.Snapshots
 }
 
+// New returns a FuelData client backed by the UK Vehicle Data API.
 func New(UkvdAPIKey string) *FuelData {
+	fd := NewWithProvider(NewUKVD(UkvdAPIKey))
+	fd.SnitchAPIKey = viper.GetString("snitch_api_key")
+	return fd
+}
+
+// NewWithProvider returns a FuelData client backed by p.
+func NewWithProvider(p Provider) *FuelData {
 	return &FuelData{
-		APIKey:       UkvdAPIKey,
-		BaseURL:      "https://uk1.ukvehicledata.co.uk",
-		SnitchAPIKey: viper.GetString("snitch_api_key"),
-		titleCaser:   cases.Title(language.English, cases.NoLower),
+		Provider: p,
 	}
 }
 
+// QueryOpts describes a fuel price search. Providers search by Postcode, or
+// by Latitude and Longitude if they support it. If Latitude and Longitude
+// are set, distances are measured from there.
 type QueryOpts struct {
-	Postcode string
-	FuelType string
-	Location string
+	Postcode  string
+	Latitude  float64
+	Longitude float64
+	FuelType  string
+	// Location, StationID, Brand and StationPostcode narrow the results
+	// down to particular stations. See StationQuery.
+	Location        string
+	StationID       string
+	Brand           string
+	StationPostcode string
+	// MaxDistance only keeps stations within this many miles. Providers
+	// which let the search radius be set use it as the radius; others, such
+	// as UKVD, pick their own and the results are filtered afterwards.
+	MaxDistance float64
+	// Amenities only keeps stations offering all of these. See Amenities.
+	Amenities []string
+	// Unique requires the station selectors to match exactly one station.
+	Unique bool
 }
 
-func (c *FuelData) doAPICall(opts QueryOpts) (*types.FuelDataResponse, error) {
-	u, _ := url.Parse(c.BaseURL)
+// StationQuery returns the station selectors in opts.
+func (o QueryOpts) StationQuery() StationQuery {
+	return StationQuery{
+		ID:       o.StationID,
+		Name:     o.Location,
+		Brand:    o.Brand,
+		Postcode: o.StationPostcode,
+	}
+}
 
-	u.Path = fuelPriceEndpoint
+// normalizePostcode checks that the postcode in o, if any, is valid before
+// it is sent to a provider and costs an API call.
+func (o *QueryOpts) normalizePostcode() error {
+	if o.Postcode == "" {
+		return nil
+	}
+	pc, err := postcode.Parse(o.Postcode)
+	if err != nil {
+		return err
+	}
+	o.Postcode = pc.Compact()
+	return nil
+}
 
-	q := u.Query()
-	q.Set("v", "2")
-	q.Set("api_nullitems", "1")
-	q.Set("auth_apikey", c.APIKey)
-	q.Set("key_POSTCODE", strings.ToUpper(opts.Postcode))
+// Result is the outcome of a Lookup: the matching prices along with the
+// provider response they were taken from.
+type Result struct {
+	*Response
+	Prices []*types.SpecificFuelPrice
+	// Snapshot is every station and price in the response, and Previous is
+	// the snapshot of the same area before it. Previous is nil if the area
+	// hasn't been looked up before, or FuelData has no Snapshots.
+	Snapshot *history.Snapshot
+	Previous *history.Snapshot
+}
 
-	u.RawQuery = q.Encode()
+// Changes compares the result with the previous lookup of the same area. It
+// returns nil if there wasn't one.
+func (r *Result) Changes(opts history.CompareOpts) []history.Change {
+	if r.Previous == nil {
+		return nil
+	}
+	return history.Compare(r.Previous, r.Snapshot, opts)
+}
 
-	res, err := http.Get(u.String())
+// GetFuelPrices takes a Postcode and a FuelType to show the stations
+// which sell that fuel in the search radius for Postcode.
+func (c *FuelData) GetFuelPrices(ctx context.Context, opts QueryOpts) ([]*types.SpecificFuelPrice, error) {
+	res, err := c.Lookup(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
+	return res.Prices, nil
+}
+
+// Lookup is like GetFuelPrices, but also returns details of the provider
+// response such as its search radius and whether it came from the cache.
+func (c *FuelData) Lookup(ctx context.Context, opts QueryOpts) (*Result, error) {
+	var prices []*types.SpecificFuelPrice
+	if opts.FuelType == "" {
+		return nil, errors.New("please specify fuel type")
+	}
 
-	defer res.Body.Close()
-	body, err := io.ReadAll(res.Body)
+	ft, err := ParseFuelType(opts.FuelType)
 	if err != nil {
 		return nil, err
 	}
+	opts.FuelType = ft
 
-	data := types.RawAPIResponse{}
-
-	err = json.Unmarshal(body, &data)
-	if err != nil {
+	if err := opts.normalizePostcode(); err != nil {
 		return nil, err
 	}
 
-	if data.Response.StatusCode != "Success" {
-		return nil, errors.New(data.Response.StatusMessage)
+	amenities := make([]string, len(opts.Amenities))
+	for i, a := range opts.Amenities {
+		if amenities[i], err = ParseAmenity(a); err != nil {
+			return nil, err
+		}
 	}
-	return &data.Response, nil
-}
+	opts.Amenities = amenities
 
-// GetFuelPrices takes a Postcode and a FuelType to show the stations
-// which sell that fuel in the search radius for Postcode.
-func (c *FuelData) GetFuelPrices(opts QueryOpts) ([]*types.SpecificFuelPrice, error) {
-	var prices []*types.SpecificFuelPrice
-	if opts.FuelType == "" {
-		return nil, errors.New("please specify fuel type")
+	res, err := c.Provider.Stations(ctx, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	// Title case the fuel type for matching on later
-	opts.FuelType = c.titleCaser.String(opts.FuelType)
+	// Providers which only search by postcode measure distances from the
+	// middle of it, so measure from the exact point instead if we have one.
+	if opts.Latitude != 0 || opts.Longitude != 0 {
+		measureFrom(res.Stations, opts.Latitude, opts.Longitude)
+	}
 
-	fd, err := c.doAPICall(opts)
+	c.observe(res)
+	c.recordHistory(res)
+
+	stations, err := c.matchStations(res.Stations, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, stn := range fd.DataItems.FuelStationDetails.FuelStationList {
-		// If the Location query param is set, skip through the list until we
-		// find a fuel station that matches.
-		if opts.Location != "" {
-			if stn.Name != opts.Location {
-				continue
+	for _, stn := range stations {
+		if opts.MaxDistance > 0 && stn.DistanceFromSearchPostcode > opts.MaxDistance {
+			continue
+		}
+		if !hasAmenities(stn, opts.Amenities) {
+			continue
+		}
+
+		if opts.FuelType == FuelTypeEV {
+			if sellsFuel(stn, FuelTypeEV) {
+				prices = append(prices, stationPrice(stn, FuelTypeEV))
 			}
+			continue
 		}
 
-		switch opts.FuelType {
-		case FuelTypeUnleaded:
-			if stn.Features.Fuel.HasUnleaded {
-				prices = append(prices, filterPriceByFuel(stn, FuelTypeUnleaded))
+		if opts.FuelType != FuelTypeAll {
+			if sellsFuel(stn, opts.FuelType) {
+				sfp, err := filterPriceByFuel(stn, opts.FuelType)
+				if err != nil {
+					log.Printf("%v", err)
+				}
+				// Skip stations which sell the fuel but haven't reported
+				// a price for it.
+				if sfp.Station != "" {
+					prices = append(prices, sfp)
+				}
 			}
-		case FuelTypeSuperUnleaded:
-			if stn.Features.Fuel.HasSuperUnleaded {
-				prices = append(prices, filterPriceByFuel(stn, FuelTypeSuperUnleaded))
+			continue
+		}
+
+		for _, ft := range pricedFuelTypes() {
+			if !sellsFuel(stn, ft) {
+				continue
 			}
-		case FuelTypeDiesel:
-			if stn.Features.Fuel.HasDiesel {
-				prices = append(prices, filterPriceByFuel(stn, FuelTypeDiesel))
+			sfp, err := filterPriceByFuel(stn, ft)
+			if err != nil {
+				log.Printf("%v", err)
 			}
-		case FuelTypePremiumDiesel:
-			if stn.Features.Fuel.HasPremiumDiesel {
-				prices = append(prices, filterPriceByFuel(stn, FuelTypePremiumDiesel))
+			// Skip fuels the station sells but hasn't reported a price for.
+			if sfp.Station != "" {
+				prices = append(prices, sfp)
 			}
 		}
 	}
+	for _, p := range prices {
+		p.StationID = c.stationID(p.StationID)
+	}
 	if len(prices) == 0 {
-		prices = append(prices, &types.SpecificFuelPrice{
-			Station:    "NOTHING FOUND",
-			FuelType:   "NOTHING FOUND",
-			Price:      0,
-			RecordedAt: "",
-			MonthYear:  "",
-		})
+		prices = append(prices, nothingFound())
+	}
+	// Only now the lookup has worked, so a failed one isn't compared with
+	// next time.
+	snap, prev := c.recordSnapshot(res, opts)
+	return &Result{Response: res, Prices: prices, Snapshot: snap, Previous: prev}, nil
+}
+
+// nothingFound is the record shown in place of prices when no station
+// matched.
+func nothingFound() *types.SpecificFuelPrice {
+	return &types.SpecificFuelPrice{
+		Station:  "NOTHING FOUND",
+		FuelType: "NOTHING FOUND",
+	}
+}
+
+// measureFrom sets the distance of each station with a location to its
+// distance from lat, lon.
+func measureFrom(stations []types.FuelStation, lat, lon float64) {
+	for i := range stations {
+		stn := &stations[i]
+		if stn.Latitude == 0 && stn.Longitude == 0 {
+			continue
+		}
+		stn.DistanceFromSearchPostcode = geo.Distance(lat, lon, stn.Latitude, stn.Longitude)
+	}
+}
+
+// observe records the stations in res in the registry, if there is one.
+// Fresh responses only, as cached ones would make stale names look current.
+func (c *FuelData) observe(res *Response) {
+	if c.Registry == nil || res.FromCache {
+		return
+	}
+	for _, known := range c.Registry.Observe(res.Stations, res.FetchedAt) {
+		prev := known.Names[len(known.Names)-2]
+		log.Printf("station %s has been renamed from %q (%s) to %q (%s)",
+			known.ID, prev.Name, prev.Brand, known.Current().Name, known.Current().Brand)
+	}
+	if err := c.Registry.Save(); err != nil {
+		log.Printf("saving station registry: %v", err)
+	}
+}
+
+// stationID returns the ID the registry knows the station with StationID id
+// by, so a station keeps one ID whichever provider reported it.
+func (c *FuelData) stationID(id string) string {
+	if c.Registry == nil {
+		return id
+	}
+	return c.Registry.ID(id)
+}
+
+// recordHistory adds every price in res to the history, if there is one.
+// Cached responses are recorded too, as the history ignores prices it
+// already has.
+func (c *FuelData) recordHistory(res *Response) {
+	if c.History == nil {
+		return
 	}
-	return prices, nil
+	var entries []history.Entry
+	for _, stn := range res.Stations {
+		for _, ft := range pricedFuelTypes() {
+			// Prices without a time can't be told apart, so aren't kept.
+			sfp, err := filterPriceByFuel(stn, ft)
+			if err != nil || sfp.Price == 0 {
+				continue
+			}
+			sfp.StationID = c.stationID(sfp.StationID)
+			entries = append(entries, history.NewEntry(sfp, res.FetchedAt))
+		}
+	}
+	if _, err := c.History.Add(entries); err != nil {
+		log.Printf("recording price history: %v", err)
+	}
+}
+
+// recordSnapshot takes a snapshot of res and stores it, returning it along
+// with the previous snapshot of the same area.
+func (c *FuelData) recordSnapshot(res *Response, opts QueryOpts) (snap, prev *history.Snapshot) {
+	snap = NewSnapshot(res)
+	for i := range snap.Stations {
+		snap.Stations[i].ID = c.stationID(snap.Stations[i].ID)
+	}
+	if c.Snapshots == nil {
+		return snap, nil
+	}
+	// Providers don't all find the same stations, and a wider search finds
+	// more, so keep their snapshots apart rather than reporting the
+	// difference as changes.
+	area := opts.Postcode
+	if area == "" {
+		area = fmt.Sprintf("%.3f,%.3f", opts.Latitude, opts.Longitude)
+	}
+	key := fmt.Sprintf("%s-%s-%gmi", res.Provider, area, res.SearchRadius)
+	prev, err := c.Snapshots.Record(key, snap)
+	if err != nil {
+		log.Printf("saving snapshot: %v", err)
+	}
+	return snap, prev
+}
+
+// NewSnapshot returns every station in res with the fuels it sells and their
+// prices.
+func NewSnapshot(res *Response) *history.Snapshot {
+	snap := &history.Snapshot{TakenAt: res.FetchedAt.UTC().Truncate(time.Second)}
+	for _, stn := range res.Stations {
+		s := history.SnapshotStation{
+			ID:       StationID(stn),
+			Name:     stn.Name,
+			Brand:    stn.Brand,
+			Postcode: stn.Postcode,
+			Prices:   map[string]types.Price{},
+		}
+		for _, ft := range pricedFuelTypes() {
+			sfp, _ := filterPriceByFuel(stn, ft)
+			price := sfp.Price
+			if price != 0 || sellsFuel(stn, ft) {
+				s.Prices[ft] = price
+			}
+		}
+		snap.Stations = append(snap.Stations, s)
+	}
+	return snap
 }
 
-func filterPriceByFuel(stn types.FuelStation, ft string) *types.SpecificFuelPrice {
+// matchStations narrows stations down to the ones selected in opts. If no
+// station has the name asked for, it tries names the stations used to have.
+func (c *FuelData) matchStations(stations []types.FuelStation, opts QueryOpts) ([]types.FuelStation, error) {
+	q := opts.StationQuery()
+	match := func(q StationQuery) ([]types.FuelStation, error) {
+		if !opts.Unique || q.IsZero() {
+			return MatchStations(stations, q)
+		}
+		stn, err := MatchStation(stations, q)
+		if err != nil {
+			return nil, err
+		}
+		return []types.FuelStation{stn}, nil
+	}
+	// A station may be in the results under another ID it has been seen
+	// under, so try them all.
+	if c.Registry != nil {
+		matchOne := match
+		match = func(q StationQuery) ([]types.FuelStation, error) {
+			if q.ID == "" {
+				return matchOne(q)
+			}
+			var firstErr error
+			for _, id := range c.Registry.AllIDs(q.ID) {
+				byID := q
+				byID.ID = id
+				m, err := matchOne(byID)
+				if err == nil {
+					return m, nil
+				}
+				if firstErr == nil {
+					firstErr = err
+				}
+			}
+			return nil, firstErr
+		}
+	}
+
+	matched, err := match(q)
+	if !errors.Is(err, ErrStationNotFound) || c.Registry == nil || q.Name == "" || q.ID != "" {
+		return matched, err
+	}
+
+	var found []types.FuelStation
+	for _, id := range c.Registry.IDsForName(q.Name) {
+		byID := q
+		byID.Name = ""
+		byID.ID = id
+		if m, idErr := match(byID); idErr == nil {
+			found = append(found, m...)
+		}
+	}
+	switch {
+	case len(found) == 0:
+		return nil, err
+	case opts.Unique && len(found) > 1:
+		return nil, &StationMatchError{Query: q, Kind: ErrAmbiguousStation, Candidates: found}
+	}
+	return found, nil
+}
+
+// stationPrice returns a record for fuel type ft at stn, with the details of
+// the station filled in but no price.
+func stationPrice(stn types.FuelStation, ft string) *types.SpecificFuelPrice {
+	return &types.SpecificFuelPrice{
+		Station:   stn.Name,
+		StationID: StationID(stn),
+		FuelType:  ft,
+		Brand:     stn.Brand,
+		Distance:  stn.DistanceFromSearchPostcode,
+		Street:    stn.Street,
+		Suburb:    stn.Suburb,
+		Town:      stn.Town,
+		County:    stn.County,
+		Postcode:  stn.Postcode,
+		Latitude:  stn.Latitude,
+		Longitude: stn.Longitude,
+		Features:  stn.Features,
+	}
+}
+
+// filterPriceByFuel returns the price of fuel type ft at stn. The record is
+// empty if the station hasn't reported a price for it. If the time the
+// price was recorded can't be read, the record is returned with a zero
+// RecordedAt along with an error saying why, so one bad timestamp doesn't
+// lose every other price.
+func filterPriceByFuel(stn types.FuelStation, ft string) (*types.SpecificFuelPrice, error) {
 	sfp := &types.SpecificFuelPrice{}
-	timeFormat := "1/2/2006 3:04:05 PM"
+	var timeErr error
 	for _, fp := range stn.FuelPriceList {
-		timestamp, err := time.Parse(timeFormat, fp.LatestRecordedPrice.TimeRecorded)
+		if !matchesFuelType(fp.FuelType, ft) {
+			continue
+		}
+
+		sfp = stationPrice(stn, ft)
+		sfp.Price = types.PriceFromPence(fp.LatestRecordedPrice.InPence)
+		if sfp.Price == 0 {
+			sfp.Price = types.PriceFromGBP(fp.LatestRecordedPrice.InGbp)
+		}
+		recordedAt, err := parseRecordedAt(fp.LatestRecordedPrice.TimeRecorded)
 		if err != nil {
-			log.Println(err)
+			timeErr = fmt.Errorf("%w: time of %s price at %s: %v", ErrMalformedResponse, ft, stn.Name, err)
+		}
+		sfp.RecordedAt = recordedAt
+	}
+	return sfp, timeErr
+}
+
+// parseRecordedAt parses a provider timestamp, which is in UTC, into
+// Europe/London time. An empty timestamp is the zero time.
+func parseRecordedAt(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(ukvdTimeFormat, s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.In(types.London), nil
+}
+
+// StationPrices holds every price found for a single station.
+type StationPrices struct {
+	Station   string
+	StationID string
+	Brand     string
+	Distance  float64
+	Features  types.StationFeatures
+	Prices    map[string]*types.SpecificFuelPrice
+}
+
+// ByStation groups records by station, keeping the order in which stations
+// first appear.
+func ByStation(records []*types.SpecificFuelPrice) []*StationPrices {
+	var out []*StationPrices
+	index := map[string]*StationPrices{}
+	for _, r := range records {
+		key := r.StationID
+		if key == "" {
+			key = r.Station
 		}
-		if fp.FuelType == ft {
-			sfp.Station = stn.Name
-			sfp.FuelType = ft
-			sfp.Price = fp.LatestRecordedPrice.InGbp
-			sfp.RecordedAt = timestamp.Local().Format("02/01/2006")
-			sfp.MonthYear = timestamp.Local().Format("1/2006")
+		sp, ok := index[key]
+		if !ok {
+			sp = &StationPrices{
+				Station:   r.Station,
+				StationID: r.StationID,
+				Brand:     r.Brand,
+				Distance:  r.Distance,
+				Features:  r.Features,
+				Prices:    map[string]*types.SpecificFuelPrice{},
+			}
+			index[key] = sp
+			out = append(out, sp)
 		}
+		sp.Prices[r.FuelType] = r
 	}
-	return sfp
+	return out
 }
 
+// PrintFuelPrices renders records as a table on stdout. See WriteFuelPrices.
 func PrintFuelPrices(records []*types.SpecificFuelPrice) {
+	WriteFuelPrices(os.Stdout, records)
+}
+
+// WriteFuelPrices renders records as a table to w. If they cover more than one
+// fuel type, it prints one row per station with a column per fuel.
+func WriteFuelPrices(w io.Writer, records []*types.SpecificFuelPrice) {
+	fuels := map[string]bool{}
+	for _, r := range records {
+		fuels[r.FuelType] = true
+	}
+	if len(fuels) > 1 {
+		writePriceMatrix(w, records, fuels)
+		return
+	}
+
 	// Set up the table
-	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"Location", "Fuel Type", "Price", "Last Recorded At"})
-	var tblData [][]string
+	table := tablewriter.NewWriter(w)
+	header := []string{"Location", "Brand", "Distance", "Fuel Type", "Price", "Last Recorded At"}
+	showAmenities := anyAmenities(records)
+	if showAmenities {
+		header = append(header, "Amenities")
+	}
+	table.SetHeader(header)
 
-	// Populate the table
+	// Populate the table, highlighting the cheapest price
+	cheapest := Cheapest(records)
+	highlight := colorEnabled(w)
 	for _, r := range records {
-		tblData = append(tblData, []string{r.Station, r.FuelType, fmt.Sprintf("%f", r.Price), r.RecordedAt})
+		row := []string{r.Station, r.Brand, formatDistance(r.Distance), r.FuelType, formatPrice(r.Price), formatTime(r.RecordedAt)}
+		if showAmenities {
+			row = append(row, amenityLabels(r.Features))
+		}
+		if highlight && cheapest[r.FuelType] == r {
+			table.Rich(row, rowColors(len(row), cheapestColors))
+			continue
+		}
+		table.Append(row)
+	}
+
+	table.Render()
+}
+
+// cheapestColors is how the cheapest price is highlighted.
+var cheapestColors = tablewriter.Colors{tablewriter.Bold, tablewriter.FgGreenColor}
+
+func rowColors(n int, c tablewriter.Colors) []tablewriter.Colors {
+	colors := make([]tablewriter.Colors, n)
+	for i := range colors {
+		colors[i] = c
+	}
+	return colors
+}
+
+// colorEnabled reports whether w is a terminal which wants colour.
+func colorEnabled(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func writePriceMatrix(w io.Writer, records []*types.SpecificFuelPrice, present map[string]bool) {
+	var columns []string
+	for _, ft := range pricedFuelTypes() {
+		if present[ft] {
+			columns = append(columns, ft)
+		}
 	}
 
-	// Draw the table
-	for _, v := range tblData {
-		table.Append(v)
+	table := tablewriter.NewWriter(w)
+	header := append(append([]string{"Location", "Brand", "Distance"}, columns...), "Last Recorded At")
+	showAmenities := anyAmenities(records)
+	if showAmenities {
+		header = append(header, "Amenities")
+	}
+	table.SetHeader(header)
+
+	cheapest := Cheapest(records)
+	highlight := colorEnabled(w)
+	for _, sp := range ByStation(records) {
+		row := []string{sp.Station, sp.Brand, formatDistance(sp.Distance)}
+		colors := make([]tablewriter.Colors, len(row), len(header))
+		highlighted := false
+		// Show when the most recently updated fuel was recorded.
+		var latest time.Time
+		for _, ft := range columns {
+			r, ok := sp.Prices[ft]
+			if !ok {
+				row = append(row, "-")
+				colors = append(colors, nil)
+				continue
+			}
+			row = append(row, formatPrice(r.Price))
+			if cheapest[ft] == r {
+				colors = append(colors, cheapestColors)
+				highlighted = true
+			} else {
+				colors = append(colors, nil)
+			}
+			if r.RecordedAt.After(latest) {
+				latest = r.RecordedAt
+			}
+		}
+		row = append(row, formatTime(latest))
+		colors = append(colors, nil)
+		if showAmenities {
+			row = append(row, amenityLabels(sp.Features))
+			colors = append(colors, nil)
+		}
+		if highlight && highlighted {
+			table.Rich(row, colors)
+			continue
+		}
+		table.Append(row)
 	}
 	table.Render()
 }
+
+// anyAmenities reports whether any of the records' stations list amenities,
+// as not every provider reports them.
+func anyAmenities(records []*types.SpecificFuelPrice) bool {
+	for _, r := range records {
+		if amenityLabels(r.Features) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func formatDistance(miles float64) string {
+	if miles == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f mi", miles)
+}
+
+func formatPrice(p types.Price) string {
+	if p == 0 {
+		return "-"
+	}
+	return p.String()
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.In(types.London).Format("02/01/2006 15:04")
+}
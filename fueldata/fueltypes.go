@@ -0,0 +1,149 @@
+package fueldata
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/poolski/fueltracker/types"
+)
+
+const (
+	FuelTypeUnleaded      = "Unleaded"
+	FuelTypeSuperUnleaded = "Super Unleaded"
+	FuelTypeDiesel        = "Diesel"
+	FuelTypePremiumDiesel = "Premium Diesel"
+	FuelTypeLPG           = "LPG"
+	// FuelTypeEV lists stations with EV charging. They have no price.
+	FuelTypeEV = "EV"
+	// FuelTypeAll matches every fuel a station sells.
+	FuelTypeAll = "All"
+)
+
+// FuelType describes a fuel and the other names it goes by, such as the UK
+// grade labels on the pumps.
+type FuelType struct {
+	Name    string
+	Aliases []string
+	// Sells reports whether a station sells the fuel. It is nil for
+	// FuelTypeAll.
+	Sells func(stn types.FuelStation) bool
+}
+
+// FuelTypes is the registry of fuel types, in display order.
+var FuelTypes = []FuelType{
+	{
+		Name:    FuelTypeUnleaded,
+		Aliases: []string{"petrol", "e10", "regular", "regular unleaded", "unleaded petrol", "ulp"},
+		Sells:   func(stn types.FuelStation) bool { return stn.Features.Fuel.HasUnleaded },
+	},
+	{
+		Name:    FuelTypeSuperUnleaded,
+		Aliases: []string{"super", "e5", "super petrol", "premium unleaded", "premium petrol", "sul"},
+		Sells:   func(stn types.FuelStation) bool { return stn.Features.Fuel.HasSuperUnleaded },
+	},
+	{
+		Name:    FuelTypeDiesel,
+		Aliases: []string{"b7", "derv", "regular diesel", "standard diesel"},
+		Sells:   func(stn types.FuelStation) bool { return stn.Features.Fuel.HasDiesel },
+	},
+	{
+		Name:    FuelTypePremiumDiesel,
+		Aliases: []string{"super diesel", "sdv", "b7 premium"},
+		Sells:   func(stn types.FuelStation) bool { return stn.Features.Fuel.HasPremiumDiesel },
+	},
+	{
+		Name:    FuelTypeLPG,
+		Aliases: []string{"autogas", "liquefied petroleum gas"},
+		Sells:   func(stn types.FuelStation) bool { return stn.Features.Fuel.HasLpg },
+	},
+	{
+		Name:    FuelTypeEV,
+		Aliases: []string{"ev charging", "electric", "charging"},
+		Sells:   func(stn types.FuelStation) bool { return stn.Features.Fuel.HasEvCharging },
+	},
+	{
+		Name: FuelTypeAll,
+	},
+}
+
+// fuelTypeIndex maps every normalized name and alias onto a fuel type.
+var fuelTypeIndex = map[string]*FuelType{}
+
+func init() {
+	for i := range FuelTypes {
+		ft := &FuelTypes[i]
+		fuelTypeIndex[fuelTypeKey(ft.Name)] = ft
+		for _, alias := range ft.Aliases {
+			fuelTypeIndex[fuelTypeKey(alias)] = ft
+		}
+	}
+}
+
+// fuelTypeKey normalizes a fuel name so that case, spacing and punctuation
+// don't matter, e.g. "Super-Unleaded" and "super unleaded" are the same.
+func fuelTypeKey(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if r == ' ' || r == '-' || r == '_' || r == '(' || r == ')' {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// LookupFuelType returns the fuel type that s is the name or an alias of.
+func LookupFuelType(s string) (*FuelType, bool) {
+	ft, ok := fuelTypeIndex[fuelTypeKey(s)]
+	return ft, ok
+}
+
+// ParseFuelType returns the canonical name for s, or an error listing the
+// valid fuel types if it isn't one we know.
+func ParseFuelType(s string) (string, error) {
+	ft, ok := LookupFuelType(s)
+	if !ok {
+		return "", fmt.Errorf("unknown fuel type %q, valid fuel types are: %s", s, strings.Join(ValidFuelTypes(), ", "))
+	}
+	return ft.Name, nil
+}
+
+// ValidFuelTypes describes each fuel type and its aliases, for help and
+// error messages.
+func ValidFuelTypes() []string {
+	out := make([]string, 0, len(FuelTypes))
+	for _, ft := range FuelTypes {
+		desc := strings.ToLower(ft.Name)
+		if len(ft.Aliases) > 0 {
+			desc += " (" + strings.Join(ft.Aliases, "/") + ")"
+		}
+		out = append(out, desc)
+	}
+	return out
+}
+
+// pricedFuelTypes returns the fuels FuelTypeAll expands to: everything
+// which has a price.
+func pricedFuelTypes() []string {
+	var out []string
+	for _, ft := range FuelTypes {
+		if ft.Sells != nil && ft.Name != FuelTypeEV {
+			out = append(out, ft.Name)
+		}
+	}
+	return out
+}
+
+// sellsFuel reports whether stn sells fuel type ft.
+func sellsFuel(stn types.FuelStation, ft string) bool {
+	t, ok := LookupFuelType(ft)
+	return ok && t.Sells != nil && t.Sells(stn)
+}
+
+// matchesFuelType reports whether a fuel name reported by a provider refers
+// to the canonical fuel type ft. Providers don't agree on naming and casing,
+// so this goes through the alias registry rather than comparing strings.
+func matchesFuelType(reported, ft string) bool {
+	t, ok := LookupFuelType(reported)
+	return ok && t.Name == ft
+}
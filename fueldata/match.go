@@ -0,0 +1,233 @@
+package fueldata
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/poolski/fueltracker/types"
+)
+
+// maxCandidates is how many near misses a StationMatchError lists.
+const maxCandidates = 5
+
+// Errors returned when a station can't be picked out of the results.
+var (
+	ErrStationNotFound  = errors.New("no station matches")
+	ErrAmbiguousStation = errors.New("more than one station matches")
+)
+
+// StationQuery picks stations out of a provider response. Any fields which
+// are set must all match.
+type StationQuery struct {
+	// ID is a StationID.
+	ID string
+	// Name is matched ignoring case, punctuation and extra whitespace, and
+	// may be any part of the station's name.
+	Name     string
+	Brand    string
+	Postcode string
+}
+
+func (q StationQuery) IsZero() bool {
+	return q == StationQuery{}
+}
+
+func (q StationQuery) String() string {
+	var parts []string
+	for _, p := range []struct{ k, v string }{
+		{"id", q.ID}, {"name", q.Name}, {"brand", q.Brand}, {"postcode", q.Postcode},
+	} {
+		if p.v != "" {
+			parts = append(parts, fmt.Sprintf("%s %q", p.k, p.v))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// StationMatchError is returned when a query matches no stations or, where
+// a single station is wanted, more than one. Candidates are the closest
+// stations, so the user can refine the query.
+type StationMatchError struct {
+	Query      StationQuery
+	Kind       error
+	Candidates []types.FuelStation
+}
+
+func (e *StationMatchError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v %s", e.Kind, e.Query)
+	if len(e.Candidates) > 0 {
+		b.WriteString(", did you mean one of these?")
+		for _, stn := range e.Candidates {
+			fmt.Fprintf(&b, "\n  %s  %s (%s, %s)", StationID(stn), stn.Name, stn.Brand, stn.Postcode)
+		}
+	}
+	return b.String()
+}
+
+func (e *StationMatchError) Unwrap() error {
+	return e.Kind
+}
+
+// StationID returns an identifier for a station. It is derived from the
+// station's postcode and location rather than its name, so it survives the
+// station being renamed or rebranded. It falls back to the name if there is
+// no location, and a small correction to the location can change it too,
+// so the Registry keeps track of which IDs are the same station.
+func StationID(stn types.FuelStation) string {
+	key := normalizePostcode(stn.Postcode)
+	if hasLocation(stn) {
+		// Round to roughly 100m so that most corrections don't change
+		// the ID.
+		key += fmt.Sprintf("|%.3f,%.3f", stn.Latitude, stn.Longitude)
+	} else {
+		key += "|" + normalizeName(stn.Name)
+	}
+	sum := sha1.Sum([]byte(key))
+	return hex.EncodeToString(sum[:])[:10]
+}
+
+// hasLocation reports whether the provider gave stn's coordinates.
+func hasLocation(stn types.FuelStation) bool {
+	return stn.Latitude != 0 || stn.Longitude != 0
+}
+
+// MatchStations returns the stations matching q. An exact name match is
+// preferred over a partial one. It returns a StationMatchError if nothing
+// matches.
+func MatchStations(stations []types.FuelStation, q StationQuery) ([]types.FuelStation, error) {
+	if q.IsZero() {
+		return stations, nil
+	}
+
+	var exact, partial []types.FuelStation
+	name := normalizeName(q.Name)
+	for _, stn := range stations {
+		if q.ID != "" && !strings.EqualFold(StationID(stn), q.ID) {
+			continue
+		}
+		if q.Brand != "" && normalizeName(stn.Brand) != normalizeName(q.Brand) {
+			continue
+		}
+		if q.Postcode != "" && normalizePostcode(stn.Postcode) != normalizePostcode(q.Postcode) {
+			continue
+		}
+		switch stnName := normalizeName(stn.Name); {
+		case name == "" || stnName == name:
+			exact = append(exact, stn)
+		case strings.Contains(stnName, name):
+			partial = append(partial, stn)
+		}
+	}
+
+	if len(exact) > 0 {
+		return exact, nil
+	}
+	if len(partial) > 0 {
+		return partial, nil
+	}
+	return nil, &StationMatchError{
+		Query:      q,
+		Kind:       ErrStationNotFound,
+		Candidates: closestStations(stations, q),
+	}
+}
+
+// MatchStation is like MatchStations, but returns a StationMatchError if
+// more than one station matches.
+func MatchStation(stations []types.FuelStation, q StationQuery) (types.FuelStation, error) {
+	matches, err := MatchStations(stations, q)
+	if err != nil {
+		return types.FuelStation{}, err
+	}
+	// The same station can turn up more than once, e.g. from overlapping
+	// feeds, so compare IDs rather than counting results.
+	for _, stn := range matches[1:] {
+		if StationID(stn) != StationID(matches[0]) {
+			if len(matches) > maxCandidates {
+				matches = matches[:maxCandidates]
+			}
+			return types.FuelStation{}, &StationMatchError{Query: q, Kind: ErrAmbiguousStation, Candidates: matches}
+		}
+	}
+	return matches[0], nil
+}
+
+// closestStations returns the stations whose names are most similar to the
+// query, for suggesting alternatives.
+func closestStations(stations []types.FuelStation, q StationQuery) []types.FuelStation {
+	target := normalizeName(q.Name)
+	if target == "" {
+		target = normalizeName(q.Brand)
+	}
+
+	type scored struct {
+		stn  types.FuelStation
+		dist int
+	}
+	var all []scored
+	for _, stn := range stations {
+		d := editDistance(normalizeName(stn.Name), target)
+		if q.Postcode != "" && normalizePostcode(stn.Postcode) == normalizePostcode(q.Postcode) {
+			d = 0
+		}
+		all = append(all, scored{stn, d})
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].dist < all[j].dist
+	})
+
+	var out []types.FuelStation
+	for i := 0; i < len(all) && i < maxCandidates; i++ {
+		out = append(out, all[i].stn)
+	}
+	return out
+}
+
+// normalizeName upper-cases s, drops punctuation and collapses whitespace.
+func normalizeName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return ' '
+	}, s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func minInt(vals ...int) int {
+	m := vals[0]
+	for _, v := range vals[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
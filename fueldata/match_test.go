@@ -0,0 +1,92 @@
+package fueldata
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/poolski/fueltracker/types"
+)
+
+func TestMatchStations(t *testing.T) {
+	stations := []types.FuelStation{
+		{Name: "Tesco Extra", Brand: "TESCO", Postcode: "SW1A 1AA", Latitude: 51.501, Longitude: -0.141},
+		{Name: "Tesco Express", Brand: "TESCO", Postcode: "SW1A 2AA", Latitude: 51.503, Longitude: -0.127},
+		{Name: "Victoria Service Station", Brand: "BP", Postcode: "SW1V 1AA", Latitude: 51.495, Longitude: -0.144},
+		{Name: "Victoria", Brand: "SHELL", Postcode: "SW1V 2AA", Latitude: 51.496, Longitude: -0.145},
+	}
+	tests := []struct {
+		name    string
+		q       StationQuery
+		want    []string
+		wantErr error
+	}{
+		{name: "no query", q: StationQuery{}, want: []string{"Tesco Extra", "Tesco Express", "Victoria Service Station", "Victoria"}},
+		{name: "exact name", q: StationQuery{Name: "tesco  extra"}, want: []string{"Tesco Extra"}},
+		{name: "exact beats partial", q: StationQuery{Name: "Victoria"}, want: []string{"Victoria"}},
+		{name: "partial name", q: StationQuery{Name: "service"}, want: []string{"Victoria Service Station"}},
+		{name: "several partial", q: StationQuery{Name: "tesco ex"}, want: []string{"Tesco Extra", "Tesco Express"}},
+		{name: "brand", q: StationQuery{Brand: "bp"}, want: []string{"Victoria Service Station"}},
+		{name: "brand and postcode", q: StationQuery{Brand: "Tesco", Postcode: "sw1a2aa"}, want: []string{"Tesco Express"}},
+		{name: "id", q: StationQuery{ID: StationID(stations[3])}, want: []string{"Victoria"}},
+		{name: "no match", q: StationQuery{Name: "Sainsburys"}, wantErr: ErrStationNotFound},
+		{name: "brand rules out name", q: StationQuery{Name: "Tesco Extra", Brand: "BP"}, wantErr: ErrStationNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MatchStations(stations, tt.q)
+			if tt.wantErr != nil {
+				var matchErr *StationMatchError
+				if !errors.Is(err, tt.wantErr) || !errors.As(err, &matchErr) {
+					t.Fatalf("got error %v, want %v", err, tt.wantErr)
+				}
+				if len(matchErr.Candidates) == 0 {
+					t.Error("error suggests no candidates")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d stations, want %v", len(got), tt.want)
+			}
+			for i, stn := range got {
+				if stn.Name != tt.want[i] {
+					t.Errorf("station %d = %q, want %q", i, stn.Name, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMatchStation(t *testing.T) {
+	tesco := types.FuelStation{Name: "Tesco Extra", Brand: "TESCO", Postcode: "SW1A 1AA", Latitude: 51.501, Longitude: -0.141}
+	stations := []types.FuelStation{
+		tesco,
+		{Name: "Tesco Express", Brand: "TESCO", Postcode: "SW1A 2AA", Latitude: 51.503, Longitude: -0.127},
+		// The same station again, as from overlapping feeds.
+		tesco,
+	}
+	tests := []struct {
+		name    string
+		q       StationQuery
+		want    string
+		wantErr error
+	}{
+		{name: "unique", q: StationQuery{Name: "Tesco Express"}, want: "Tesco Express"},
+		{name: "duplicates of one station", q: StationQuery{Name: "Tesco Extra"}, want: "Tesco Extra"},
+		{name: "ambiguous", q: StationQuery{Brand: "Tesco"}, wantErr: ErrAmbiguousStation},
+		{name: "none", q: StationQuery{Brand: "Esso"}, wantErr: ErrStationNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MatchStation(stations, tt.q)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if got.Name != tt.want {
+				t.Errorf("got %q, want %q", got.Name, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package fueldata
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/poolski/fueltracker/config"
+	"github.com/poolski/fueltracker/history"
+	"github.com/poolski/fueltracker/types"
+)
+
+// DefaultProvider is the provider used when none is configured.
+const DefaultProvider = "ukvd"
+
+// Provider is a source of fuel station and price data.
+type Provider interface {
+	// Name returns the name the provider is registered under.
+	Name() string
+	// Stations returns the stations near the postcode or coordinates in opts.
+	Stations(ctx context.Context, opts QueryOpts) (*Response, error)
+}
+
+// Response is the normalized result of a provider query.
+type Response struct {
+	Provider string
+	// SearchRadius is how far, in miles, the provider searched. Some
+	// providers widen the search when there are few stations nearby.
+	SearchRadius float64
+	Stations     []types.FuelStation
+	// FetchedAt is when the data was fetched from the provider, which is in
+	// the past if FromCache is set.
+	FetchedAt time.Time
+	FromCache bool
+}
+
+// Age returns how long ago the response was fetched.
+func (r *Response) Age() time.Duration {
+	return time.Since(r.FetchedAt)
+}
+
+// ProviderFactory builds a Provider from the tool's config.
+type ProviderFactory func(cfg *config.Config) (Provider, error)
+
+var providers = map[string]ProviderFactory{}
+
+// RegisterProvider makes a provider available for selection in the config
+// under the given name. It panics if the name is already taken.
+func RegisterProvider(name string, factory ProviderFactory) {
+	name = strings.ToLower(name)
+	if _, ok := providers[name]; ok {
+		panic(fmt.Sprintf("fueldata: provider %q registered twice", name))
+	}
+	providers[name] = factory
+}
+
+// Providers returns the names of all registered providers.
+func Providers() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewProvider builds the named provider from cfg.
+func NewProvider(name string, cfg *config.Config) (Provider, error) {
+	factory, ok := providers[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q, valid providers are: %s", name, strings.Join(Providers(), ", "))
+	}
+	return factory(cfg)
+}
+
+// ProviderFromConfig builds the provider selected in cfg. If fallback
+// providers are configured, they are chained behind it.
+func ProviderFromConfig(cfg *config.Config) (Provider, error) {
+	name := cfg.Provider
+	if name == "" {
+		name = DefaultProvider
+	}
+	p, err := NewProvider(name, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.FallbackProviders) == 0 {
+		return p, nil
+	}
+
+	chain := NewChain(p)
+	for _, name := range cfg.FallbackProviders {
+		fallback, err := NewProvider(name, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("fallback provider: %w", err)
+		}
+		chain.Providers = append(chain.Providers, fallback)
+	}
+	return chain, nil
+}
+
+// FromConfig returns a FuelData client backed by the providers selected in
+// cfg.
+func FromConfig(cfg *config.Config) (*FuelData, error) {
+	p, err := ProviderFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	fd := NewWithProvider(p)
+	fd.SnitchAPIKey = cfg.SnitchAPIKey
+
+	if fd.Registry, err = RegistryFromConfig(cfg); err != nil {
+		return nil, err
+	}
+
+	if fd.History, err = HistoryFromConfig(cfg); err != nil {
+		return nil, err
+	}
+	fd.Snapshots = history.SnapshotsFor(fd.History.Path())
+	return fd, nil
+}
+
+// HistoryFromConfig opens the price history configured in cfg.
+func HistoryFromConfig(cfg *config.Config) (*history.Store, error) {
+	path := cfg.HistoryFile
+	if path == "" {
+		var err error
+		if path, err = history.DefaultPath(); err != nil {
+			return nil, err
+		}
+	}
+	return history.Open(path), nil
+}
+
+// RegistryFromConfig loads the station registry configured in cfg.
+func RegistryFromConfig(cfg *config.Config) (*Registry, error) {
+	path := cfg.StationsFile
+	if path == "" {
+		var err error
+		if path, err = DefaultRegistryPath(); err != nil {
+			return nil, err
+		}
+	}
+	return LoadRegistry(path)
+}
@@ -0,0 +1,147 @@
+package fueldata
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/poolski/fueltracker/types"
+)
+
+// SortKey is an order to rank prices in.
+type SortKey string
+
+const (
+	// SortNone keeps the order the provider returned.
+	SortNone     SortKey = ""
+	SortPrice    SortKey = "price"
+	SortDistance SortKey = "distance"
+	// SortAge puts the most recently reported prices first.
+	SortAge SortKey = "age"
+	// SortValue ranks by ValueScore, which weighs price against distance.
+	SortValue SortKey = "value"
+)
+
+// DefaultPencePerMile is how many pence per litre each mile of distance is
+// worth when ranking by value, if not configured.
+const DefaultPencePerMile = 1.0
+
+var sortKeys = []SortKey{SortPrice, SortDistance, SortAge, SortValue}
+
+// ParseSortKey returns the SortKey named by s.
+func ParseSortKey(s string) (SortKey, error) {
+	if s == "" {
+		return SortNone, nil
+	}
+	for _, k := range sortKeys {
+		if strings.EqualFold(s, string(k)) {
+			return k, nil
+		}
+	}
+	names := make([]string, len(sortKeys))
+	for i, k := range sortKeys {
+		names[i] = string(k)
+	}
+	return SortNone, fmt.Errorf("unknown sort order %q, valid orders are: %s", s, strings.Join(names, ", "))
+}
+
+// RankOpts controls how Rank orders and trims prices.
+type RankOpts struct {
+	By SortKey
+	// Limit keeps only the first Limit stations if it is above zero. In
+	// all-fuels mode a station can have several prices, which are all kept.
+	Limit int
+	// PencePerMile is used by SortValue. It defaults to DefaultPencePerMile.
+	PencePerMile float64
+	// Now is used by SortAge. It defaults to the current time.
+	Now time.Time
+}
+
+// ValueScore returns the price in pence per litre plus pencePerMile for
+// every mile to the station. Lower is better.
+func ValueScore(r *types.SpecificFuelPrice, pencePerMile float64) float64 {
+	return r.Price.Pence() + r.Distance*pencePerMile
+}
+
+// Rank returns a sorted copy of records. Records without a price always
+// sort last, as there's nothing to compare them on, and so do records of
+// unknown age when sorting by age.
+func Rank(records []*types.SpecificFuelPrice, opts RankOpts) []*types.SpecificFuelPrice {
+	out := make([]*types.SpecificFuelPrice, len(records))
+	copy(out, records)
+
+	if opts.PencePerMile == 0 {
+		opts.PencePerMile = DefaultPencePerMile
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+
+	var less func(a, b *types.SpecificFuelPrice) bool
+	switch opts.By {
+	case SortPrice:
+		less = func(a, b *types.SpecificFuelPrice) bool { return a.Price < b.Price }
+	case SortDistance:
+		less = func(a, b *types.SpecificFuelPrice) bool { return a.Distance < b.Distance }
+	case SortAge:
+		less = func(a, b *types.SpecificFuelPrice) bool {
+			// Age is zero if it's unknown, which would sort first, so
+			// put those last instead.
+			if a.RecordedAt.IsZero() != b.RecordedAt.IsZero() {
+				return b.RecordedAt.IsZero()
+			}
+			return a.Age(opts.Now) < b.Age(opts.Now)
+		}
+	case SortValue:
+		less = func(a, b *types.SpecificFuelPrice) bool {
+			return ValueScore(a, opts.PencePerMile) < ValueScore(b, opts.PencePerMile)
+		}
+	}
+
+	if less != nil {
+		sort.SliceStable(out, func(i, j int) bool {
+			a, b := out[i], out[j]
+			if (a.Price == 0) != (b.Price == 0) {
+				return b.Price == 0
+			}
+			return less(a, b)
+		})
+	}
+
+	if opts.Limit > 0 {
+		return limitStations(out, opts.Limit)
+	}
+	return out
+}
+
+// limitStations returns the records for the first n stations in records.
+func limitStations(records []*types.SpecificFuelPrice, n int) []*types.SpecificFuelPrice {
+	seen := map[string]bool{}
+	var out []*types.SpecificFuelPrice
+	for _, r := range records {
+		key := r.StationID + "|" + r.Station
+		if !seen[key] {
+			if len(seen) == n {
+				continue
+			}
+			seen[key] = true
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// Cheapest returns the cheapest priced record of each fuel type.
+func Cheapest(records []*types.SpecificFuelPrice) map[string]*types.SpecificFuelPrice {
+	out := map[string]*types.SpecificFuelPrice{}
+	for _, r := range records {
+		if r.Price == 0 {
+			continue
+		}
+		if cur, ok := out[r.FuelType]; !ok || r.Price < cur.Price {
+			out[r.FuelType] = r
+		}
+	}
+	return out
+}
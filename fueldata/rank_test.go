@@ -0,0 +1,88 @@
+package fueldata
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/poolski/fueltracker/types"
+)
+
+func TestRank(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	price := func(station string, pence, miles float64, age time.Duration) *types.SpecificFuelPrice {
+		r := &types.SpecificFuelPrice{Station: station, StationID: station, FuelType: FuelTypeUnleaded, Price: types.PriceFromPence(pence), Distance: miles}
+		if age > 0 {
+			r.RecordedAt = now.Add(-age)
+		}
+		return r
+	}
+	records := []*types.SpecificFuelPrice{
+		price("A", 145.9, 1.0, 2*time.Hour),
+		price("B", 139.9, 4.0, 0),
+		price("C", 0, 0.5, time.Hour),
+		price("D", 141.9, 1.0, 30*time.Minute),
+		price("E", 139.9, 2.0, 3*time.Hour),
+	}
+
+	tests := []struct {
+		name string
+		opts RankOpts
+		want string
+	}{
+		{name: "provider order", opts: RankOpts{}, want: "ABCDE"},
+		// B and E tie, so keep their order. C has no price.
+		{name: "price", opts: RankOpts{By: SortPrice}, want: "BEDAC"},
+		{name: "distance", opts: RankOpts{By: SortDistance}, want: "ADEBC"},
+		// B's age is unknown.
+		{name: "age", opts: RankOpts{By: SortAge}, want: "DAEBC"},
+		// A 146.9, B 143.9, D 142.9, E 141.9.
+		{name: "value", opts: RankOpts{By: SortValue}, want: "EDBAC"},
+		// A 155.9, B 179.9, D 151.9, E 159.9.
+		{name: "value per mile", opts: RankOpts{By: SortValue, PencePerMile: 10}, want: "DAEBC"},
+		{name: "limit", opts: RankOpts{By: SortPrice, Limit: 2}, want: "BE"},
+		{name: "limit above count", opts: RankOpts{By: SortPrice, Limit: 10}, want: "BEDAC"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Now = now
+			if got := stationOrder(Rank(records, tt.opts)); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if got := stationOrder(records); got != "ABCDE" {
+		t.Errorf("Rank reordered its input to %s", got)
+	}
+}
+
+func TestRankLimitKeepsEveryFuel(t *testing.T) {
+	var records []*types.SpecificFuelPrice
+	for _, r := range []struct {
+		station, fuel string
+		pence         float64
+	}{
+		{"A", FuelTypeUnleaded, 139.9},
+		{"B", FuelTypeUnleaded, 141.9},
+		{"A", FuelTypeDiesel, 149.9},
+		{"C", FuelTypeDiesel, 145.9},
+		{"B", FuelTypeDiesel, 147.9},
+	} {
+		records = append(records, &types.SpecificFuelPrice{Station: r.station, StationID: r.station, FuelType: r.fuel, Price: types.PriceFromPence(r.pence)})
+	}
+
+	got := Rank(records, RankOpts{By: SortPrice, Limit: 2})
+	if order := stationOrder(got); order != "ABBA" {
+		t.Errorf("got %s, want ABBA", order)
+	}
+}
+
+// stationOrder returns the stations of records in order, as one string.
+func stationOrder(records []*types.SpecificFuelPrice) string {
+	var b strings.Builder
+	for _, r := range records {
+		b.WriteString(r.Station)
+	}
+	return b.String()
+}
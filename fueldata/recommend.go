@@ -0,0 +1,162 @@
+package fueldata
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/poolski/fueltracker/types"
+)
+
+// litresPerGallon is the size of an imperial gallon, which UK economy
+// figures are quoted in.
+const litresPerGallon = 4.54609
+
+// Vehicle describes the car being filled up, for working out whether a
+// cheaper station is worth the drive.
+type Vehicle struct {
+	// MPG is the fuel economy in miles per imperial gallon.
+	MPG float64
+	// FillLitres is how much fuel is bought in a typical fill-up.
+	FillLitres float64
+}
+
+// Validate reports whether v has what Recommend needs.
+func (v Vehicle) Validate() error {
+	if v.MPG <= 0 {
+		return errors.New("vehicle fuel economy (mpg) must be above zero")
+	}
+	if v.FillLitres <= 0 {
+		return errors.New("vehicle fill volume (litres) must be above zero")
+	}
+	return nil
+}
+
+// LitresPerMile is how much fuel v burns per mile.
+func (v Vehicle) LitresPerMile() float64 {
+	return litresPerGallon / v.MPG
+}
+
+// Recommendation is what a fill-up at a station costs once the drive there
+// and back is paid for. All amounts are in pence.
+type Recommendation struct {
+	*types.SpecificFuelPrice
+	// FillCost is the cost of FillLitres at the pump.
+	FillCost float64
+	// TripCost is the fuel burnt driving to the station and back.
+	TripCost float64
+	// NetSaving is how much less the fill-up costs in total than at the
+	// nearest station. It is negative if the station is worse value.
+	NetSaving float64
+	// BreakEven is the furthest away the station could be before it stops
+	// beating the nearest station, or zero if it never does.
+	BreakEven float64
+}
+
+// Total is what the fill-up costs including the trip.
+func (r *Recommendation) Total() float64 {
+	return r.FillCost + r.TripCost
+}
+
+// Recommend works out the net saving of filling up at each priced station
+// compared to the nearest one, and returns them best first. The records
+// should all be for the same fuel type.
+func Recommend(records []*types.SpecificFuelPrice, v Vehicle) ([]*Recommendation, error) {
+	if err := v.Validate(); err != nil {
+		return nil, err
+	}
+
+	var recs []*Recommendation
+	fuels := map[string]bool{}
+	for _, r := range records {
+		if r.Price == 0 {
+			continue
+		}
+		fuels[r.FuelType] = true
+		pence := r.Price.Pence()
+		recs = append(recs, &Recommendation{
+			SpecificFuelPrice: r,
+			FillCost:          pence * v.FillLitres,
+			TripCost:          2 * r.Distance * v.LitresPerMile() * pence,
+		})
+	}
+	if len(recs) == 0 {
+		return nil, ErrNoStations
+	}
+	if len(fuels) > 1 {
+		return nil, fmt.Errorf("can only recommend a station for one fuel type at a time, got %d", len(fuels))
+	}
+
+	nearest := recs[0]
+	for _, r := range recs[1:] {
+		if r.Distance < nearest.Distance {
+			nearest = r
+		}
+	}
+	baseline := nearest.Total()
+
+	for _, r := range recs {
+		r.NetSaving = baseline - r.Total()
+		// Solve FillCost + 2*d*LitresPerMile*price = baseline for d.
+		d := (baseline - r.FillCost) / (2 * v.LitresPerMile() * r.Price.Pence())
+		if d > 0 {
+			r.BreakEven = d
+		}
+	}
+
+	sort.SliceStable(recs, func(i, j int) bool {
+		return recs[i].NetSaving > recs[j].NetSaving
+	})
+	return recs, nil
+}
+
+// WriteRecommendations renders recs to w as a table, followed by a summary
+// of the best option.
+func WriteRecommendations(w io.Writer, recs []*Recommendation, v Vehicle) {
+	if len(recs) == 0 {
+		return
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Location", "Brand", "Distance", "Price", "Fill Cost", "Trip Cost", "Net Saving", "Break-even"})
+
+	highlight := colorEnabled(w)
+	for i, r := range recs {
+		row := []string{
+			r.Station,
+			r.Brand,
+			formatDistance(r.Distance),
+			formatPrice(r.Price),
+			formatMoney(r.FillCost),
+			formatMoney(r.TripCost),
+			formatMoney(r.NetSaving),
+			formatDistance(r.BreakEven),
+		}
+		if highlight && i == 0 {
+			table.Rich(row, rowColors(len(row), cheapestColors))
+			continue
+		}
+		table.Append(row)
+	}
+	table.Render()
+
+	best := recs[0]
+	fmt.Fprintf(w, "Best option for %.0f litres at %.0f mpg: %s (%s), %.1f mi away, %s in total.\n",
+		v.FillLitres, v.MPG, best.Station, best.Price, best.Distance, formatMoney(best.Total()))
+	if best.NetSaving > 0 {
+		fmt.Fprintf(w, "That saves %s over the nearest station, and would still be worth it up to %.1f mi away.\n",
+			formatMoney(best.NetSaving), best.BreakEven)
+	} else {
+		fmt.Fprintln(w, "Nowhere is cheap enough to be worth driving further than the nearest station.")
+	}
+}
+
+// formatMoney formats an amount in pence as pounds.
+func formatMoney(pence float64) string {
+	if pence < 0 {
+		return fmt.Sprintf("-£%.2f", -pence/100)
+	}
+	return fmt.Sprintf("£%.2f", pence/100)
+}
@@ -0,0 +1,93 @@
+package fueldata
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/poolski/fueltracker/types"
+)
+
+func TestRecommend(t *testing.T) {
+	// Burns exactly a litre a mile, to keep the sums simple.
+	v := Vehicle{MPG: litresPerGallon, FillLitres: 50}
+	price := func(station string, pence, miles float64) *types.SpecificFuelPrice {
+		return &types.SpecificFuelPrice{Station: station, FuelType: FuelTypeUnleaded, Price: types.PriceFromPence(pence), Distance: miles}
+	}
+	records := []*types.SpecificFuelPrice{
+		price("EXPENSIVE", 160, 2),
+		price("NEAREST", 150, 1),
+		price("UNPRICED", 0, 0.2),
+		price("CHEAP", 130, 3),
+	}
+
+	recs, err := Recommend(records, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The nearest costs 7500p to fill plus 300p to get to, 7800p in all.
+	want := []struct {
+		station   string
+		total     float64
+		netSaving float64
+		breakEven float64
+	}{
+		// 6500p plus 780p. It could be (7800-6500)/260 = 5 miles away.
+		{"CHEAP", 7280, 520, 5},
+		{"NEAREST", 7800, 0, 1},
+		// The fill alone costs more than the nearest station.
+		{"EXPENSIVE", 8640, -840, 0},
+	}
+	if len(recs) != len(want) {
+		t.Fatalf("got %d recommendations, want %d", len(recs), len(want))
+	}
+	for i, w := range want {
+		r := recs[i]
+		if r.Station != w.station {
+			t.Errorf("recommendation %d is %s, want %s", i, r.Station, w.station)
+			continue
+		}
+		for _, c := range []struct {
+			name      string
+			got, want float64
+		}{
+			{"total", r.Total(), w.total},
+			{"net saving", r.NetSaving, w.netSaving},
+			{"break-even", r.BreakEven, w.breakEven},
+		} {
+			if math.Abs(c.got-c.want) > 1e-9 {
+				t.Errorf("%s %s = %v, want %v", r.Station, c.name, c.got, c.want)
+			}
+		}
+	}
+}
+
+func TestRecommendErrors(t *testing.T) {
+	unleaded := &types.SpecificFuelPrice{Station: "A", FuelType: FuelTypeUnleaded, Price: types.PriceFromPence(140)}
+	diesel := &types.SpecificFuelPrice{Station: "A", FuelType: FuelTypeDiesel, Price: types.PriceFromPence(150)}
+	unpriced := &types.SpecificFuelPrice{Station: "B", FuelType: FuelTypeUnleaded}
+	car := Vehicle{MPG: 45, FillLitres: 40}
+
+	tests := []struct {
+		name    string
+		records []*types.SpecificFuelPrice
+		v       Vehicle
+		wantIs  error
+	}{
+		{name: "no mpg", records: []*types.SpecificFuelPrice{unleaded}, v: Vehicle{FillLitres: 40}},
+		{name: "no fill", records: []*types.SpecificFuelPrice{unleaded}, v: Vehicle{MPG: 45}},
+		{name: "no prices", records: []*types.SpecificFuelPrice{unpriced}, v: car, wantIs: ErrNoStations},
+		{name: "mixed fuels", records: []*types.SpecificFuelPrice{unleaded, diesel}, v: car},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Recommend(tt.records, tt.v)
+			if err == nil {
+				t.Fatal("got no error")
+			}
+			if tt.wantIs != nil && !errors.Is(err, tt.wantIs) {
+				t.Errorf("got error %v, want %v", err, tt.wantIs)
+			}
+		})
+	}
+}
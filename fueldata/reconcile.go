@@ -0,0 +1,183 @@
+package fueldata
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/poolski/fueltracker/geo"
+	"github.com/poolski/fueltracker/postcode"
+	"github.com/poolski/fueltracker/types"
+)
+
+// Observation is a price reported by a single provider.
+type Observation struct {
+	Provider   string
+	Pence      float64
+	RecordedAt string
+}
+
+// Discrepancy is a station and fuel for which two providers disagree on the
+// price.
+type Discrepancy struct {
+	Station  string
+	Postcode string
+	FuelType string
+	A        Observation
+	B        Observation
+}
+
+// Delta returns the absolute price difference in pence.
+func (d Discrepancy) Delta() float64 {
+	return math.Abs(d.A.Pence - d.B.Pence)
+}
+
+// Reconcile queries providers a and b for the same search and returns every
+// station and fuel where their prices differ by more than thresholdPence.
+// Stations are matched on postcode, then StationID or location. If
+// opts.FuelType is set, only that fuel is compared.
+func Reconcile(ctx context.Context, opts QueryOpts, a, b Provider, thresholdPence float64) ([]Discrepancy, error) {
+	if opts.FuelType != "" {
+		ft, err := ParseFuelType(opts.FuelType)
+		if err != nil {
+			return nil, err
+		}
+		// Comparing every fuel is the same as not filtering.
+		if ft == FuelTypeAll {
+			ft = ""
+		}
+		opts.FuelType = ft
+	}
+	if err := opts.normalizePostcode(); err != nil {
+		return nil, err
+	}
+
+	resA, err := a.Stations(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("querying %s: %w", a.Name(), err)
+	}
+	resB, err := b.Stations(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("querying %s: %w", b.Name(), err)
+	}
+
+	byPostcode := map[string][]types.FuelStation{}
+	for _, stn := range resB.Stations {
+		pc := normalizePostcode(stn.Postcode)
+		byPostcode[pc] = append(byPostcode[pc], stn)
+	}
+
+	var out []Discrepancy
+	for _, stnA := range resA.Stations {
+		stnB, ok := pairStation(stnA, byPostcode[normalizePostcode(stnA.Postcode)])
+		if !ok {
+			continue
+		}
+		for _, fpA := range stnA.FuelPriceList {
+			ftA, ok := LookupFuelType(fpA.FuelType)
+			if !ok || (opts.FuelType != "" && ftA.Name != opts.FuelType) {
+				continue
+			}
+			for _, fpB := range stnB.FuelPriceList {
+				if !matchesFuelType(fpB.FuelType, ftA.Name) {
+					continue
+				}
+				d := Discrepancy{
+					Station:  stnA.Name,
+					Postcode: stnA.Postcode,
+					FuelType: ftA.Name,
+					A:        observe(resA.Provider, fpA),
+					B:        observe(resB.Provider, fpB),
+				}
+				// A missing price isn't a disagreement about the price.
+				if d.A.Pence == 0 || d.B.Pence == 0 {
+					continue
+				}
+				if d.Delta() > thresholdPence {
+					out = append(out, d)
+				}
+			}
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Delta() > out[j].Delta()
+	})
+	return out, nil
+}
+
+// pairStation returns the station in candidates, which share stn's
+// postcode, that is the same forecourt as stn. Several forecourts can share
+// a postcode, e.g. at motorway services, so it prefers the same StationID,
+// then the nearest. Without coordinates to go on, only a single candidate
+// is a match.
+func pairStation(stn types.FuelStation, candidates []types.FuelStation) (types.FuelStation, bool) {
+	id := StationID(stn)
+	for _, c := range candidates {
+		if StationID(c) == id {
+			return c, true
+		}
+	}
+	if len(candidates) == 1 {
+		return candidates[0], true
+	}
+	if !hasLocation(stn) {
+		return types.FuelStation{}, false
+	}
+
+	var best types.FuelStation
+	bestDist := math.Inf(1)
+	for _, c := range candidates {
+		if !hasLocation(c) {
+			continue
+		}
+		if d := geo.Distance(stn.Latitude, stn.Longitude, c.Latitude, c.Longitude); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best, !math.IsInf(bestDist, 1)
+}
+
+func observe(provider string, fp types.FuelPrice) Observation {
+	price := types.PriceFromPence(fp.LatestRecordedPrice.InPence)
+	if price == 0 {
+		price = types.PriceFromGBP(fp.LatestRecordedPrice.InGbp)
+	}
+	return Observation{
+		Provider:   provider,
+		Pence:      price.Pence(),
+		RecordedAt: fp.LatestRecordedPrice.TimeRecorded,
+	}
+}
+
+func normalizePostcode(pc string) string {
+	return postcode.Compact(pc)
+}
+
+// WriteDiscrepancies renders ds to w as a table.
+func WriteDiscrepancies(w io.Writer, ds []Discrepancy) {
+	table := tablewriter.NewWriter(w)
+	if len(ds) == 0 {
+		fmt.Fprintln(w, "no price discrepancies found")
+		return
+	}
+	a, b := ds[0].A.Provider, ds[0].B.Provider
+	table.SetHeader([]string{"Location", "Postcode", "Fuel Type", a, a + " Recorded At", b, b + " Recorded At", "Difference"})
+
+	for _, d := range ds {
+		table.Append([]string{
+			d.Station,
+			d.Postcode,
+			d.FuelType,
+			fmt.Sprintf("%.1f", d.A.Pence),
+			d.A.RecordedAt,
+			fmt.Sprintf("%.1f", d.B.Pence),
+			d.B.RecordedAt,
+			fmt.Sprintf("%.1f", d.Delta()),
+		})
+	}
+	table.Render()
+}
@@ -0,0 +1,303 @@
+package fueldata
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/poolski/fueltracker/config"
+	"github.com/poolski/fueltracker/geo"
+	"github.com/poolski/fueltracker/types"
+)
+
+// Registry remembers every station we have seen, keyed by StationID, along
+// with the names and brands it has traded under. This lets a station be
+// found by a name it used to have, and keeps its history together when it
+// is rebranded.
+type Registry struct {
+	path string
+
+	mu       sync.Mutex
+	Stations map[string]*KnownStation `json:"stations"`
+	// Aliases maps other IDs a station has been seen under to the ID it is
+	// known by. A station gets another ID when a provider reports it at
+	// slightly different coordinates, or it is renamed and there are no
+	// coordinates to go on.
+	Aliases map[string]string `json:"aliases,omitempty"`
+}
+
+// sameStationMiles is how close a station seen under a new ID has to be to
+// a known station at the same postcode to be taken for the same one. It is
+// roughly 100m.
+const sameStationMiles = 0.06
+
+// KnownStation is a station in the Registry.
+type KnownStation struct {
+	ID        string  `json:"id"`
+	Postcode  string  `json:"postcode"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	// Names are in the order they were last seen, so the current one is
+	// last.
+	Names []NameUsage `json:"names"`
+}
+
+// NameUsage records when a station was seen under a name and brand.
+type NameUsage struct {
+	Name      string    `json:"name"`
+	Brand     string    `json:"brand"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// Current returns the name the station was most recently seen under.
+func (k *KnownStation) Current() NameUsage {
+	return k.Names[len(k.Names)-1]
+}
+
+func (k *KnownStation) hasLocation() bool {
+	return k.Latitude != 0 || k.Longitude != 0
+}
+
+// DefaultRegistryPath returns where the registry is kept if the config
+// doesn't say otherwise.
+func DefaultRegistryPath() (string, error) {
+	dir, err := config.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "stations.json"), nil
+}
+
+// LoadRegistry reads the registry at path. A missing file is an empty
+// registry, and so is one which can't be read, as the registry is only a
+// record of what has been seen and will fill up again.
+func LoadRegistry(path string) (*Registry, error) {
+	r := newRegistry(path)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, r); err != nil {
+		log.Printf("ignoring unreadable station registry %s: %v", path, err)
+		return newRegistry(path), nil
+	}
+	if r.Stations == nil {
+		r.Stations = map[string]*KnownStation{}
+	}
+	if r.Aliases == nil {
+		r.Aliases = map[string]string{}
+	}
+	return r, nil
+}
+
+func newRegistry(path string) *Registry {
+	return &Registry{path: path, Stations: map[string]*KnownStation{}, Aliases: map[string]string{}}
+}
+
+// Observe records that stations were seen at the given time. It returns the
+// stations seen under a name or brand they haven't had before.
+func (r *Registry) Observe(stations []types.FuelStation, at time.Time) []*KnownStation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var renamed []*KnownStation
+	// Stations in the same response are different stations, however close
+	// together they are.
+	seen := map[string]bool{}
+	for _, stn := range stations {
+		id := StationID(stn)
+		known := r.lookup(id)
+		if known == nil {
+			if known = r.nearby(stn, seen); known != nil {
+				r.Aliases[id] = known.ID
+			}
+		}
+		if known == nil {
+			r.Stations[id] = &KnownStation{
+				ID:        id,
+				Postcode:  stn.Postcode,
+				Latitude:  stn.Latitude,
+				Longitude: stn.Longitude,
+				Names:     []NameUsage{{Name: stn.Name, Brand: stn.Brand, FirstSeen: at, LastSeen: at}},
+			}
+			seen[id] = true
+			continue
+		}
+		seen[known.ID] = true
+
+		if known.seenAs(stn.Name, stn.Brand, at) {
+			continue
+		}
+		known.Names = append(known.Names, NameUsage{Name: stn.Name, Brand: stn.Brand, FirstSeen: at, LastSeen: at})
+		renamed = append(renamed, known)
+	}
+	return renamed
+}
+
+// seenAs records that the station was seen under a name and brand it has
+// had before, moving it to the end of Names as the current one. Providers
+// don't agree on names, e.g. one gives the address, so switching between
+// them isn't a rename. It reports false if the name and brand are new.
+func (k *KnownStation) seenAs(name, brand string, at time.Time) bool {
+	for i, n := range k.Names {
+		if n.Name != name || n.Brand != brand {
+			continue
+		}
+		if at.After(n.LastSeen) {
+			n.LastSeen = at
+		}
+		k.Names = append(append(k.Names[:i:i], k.Names[i+1:]...), n)
+		return true
+	}
+	return false
+}
+
+// lookup returns the station known by, or also seen under, id.
+func (r *Registry) lookup(id string) *KnownStation {
+	if known, ok := r.Stations[id]; ok {
+		return known
+	}
+	return r.Stations[r.Aliases[id]]
+}
+
+// nearby returns the known station which stn, seen under a new ID, is
+// most likely to be: the nearest at the same postcode within
+// sameStationMiles or, if there are no coordinates to compare, the only
+// one at the same postcode. Stations in skip are left out.
+func (r *Registry) nearby(stn types.FuelStation, skip map[string]bool) *KnownStation {
+	pc := normalizePostcode(stn.Postcode)
+	if pc == "" {
+		return nil
+	}
+	var candidates []*KnownStation
+	for id, known := range r.Stations {
+		if !skip[id] && normalizePostcode(known.Postcode) == pc {
+			candidates = append(candidates, known)
+		}
+	}
+
+	var best *KnownStation
+	bestDist := sameStationMiles
+	for _, known := range candidates {
+		if !hasLocation(stn) || !known.hasLocation() {
+			continue
+		}
+		if d := geo.Distance(stn.Latitude, stn.Longitude, known.Latitude, known.Longitude); d <= bestDist {
+			best, bestDist = known, d
+		}
+	}
+	if best != nil {
+		return best
+	}
+	if len(candidates) == 1 && (!hasLocation(stn) || !candidates[0].hasLocation()) {
+		return candidates[0]
+	}
+	return nil
+}
+
+// ID returns the ID the station with the given StationID is known by. It is
+// id itself unless the station has been seen under another ID before.
+func (r *Registry) ID(id string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if known := r.lookup(id); known != nil {
+		return known.ID
+	}
+	return id
+}
+
+// AllIDs returns id along with every other ID the same station has been
+// seen under.
+func (r *Registry) AllIDs(id string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	known := r.lookup(id)
+	if known == nil {
+		return []string{id}
+	}
+	ids := []string{known.ID}
+	for alias, to := range r.Aliases {
+		if to == known.ID {
+			ids = append(ids, alias)
+		}
+	}
+	sort.Strings(ids[1:])
+	return ids
+}
+
+// IDsForName returns the IDs of stations which have ever had a name
+// matching name, using the same rules as StationQuery.Name.
+func (r *Registry) IDsForName(name string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name = normalizeName(name)
+	var ids []string
+	for id, known := range r.Stations {
+		for _, n := range known.Names {
+			if strings.Contains(normalizeName(n.Name), name) {
+				ids = append(ids, id)
+				break
+			}
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// List returns the known stations ordered by current name.
+func (r *Registry) List() []*KnownStation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]*KnownStation, 0, len(r.Stations))
+	for _, known := range r.Stations {
+		out = append(out, known)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Current().Name < out[j].Current().Name
+	})
+	return out
+}
+
+// Save writes the registry back to the file it was loaded from. It is safe
+// to call from several lookups at once.
+func (r *Registry) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(r.path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so an interrupted save leaves the
+	// old registry alone.
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), r.path)
+}
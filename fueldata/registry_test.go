@@ -0,0 +1,141 @@
+package fueldata
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/poolski/fueltracker/types"
+)
+
+func TestRegistryKeepsIDs(t *testing.T) {
+	at := time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC)
+	asda := types.FuelStation{Name: "ASDA SUPERSTORE", Brand: "ASDA", Postcode: "SW1A 1AA", Latitude: 51.5014, Longitude: -0.1415}
+	bp := types.FuelStation{Name: "BP CONNECT", Brand: "BP", Postcode: "SW1A 1AA", Latitude: 51.5034, Longitude: -0.1415}
+	noLocation := types.FuelStation{Name: "SHELL HIGH ST", Brand: "SHELL", Postcode: "M1 1AA"}
+
+	tests := []struct {
+		name string
+		seen types.FuelStation
+		want types.FuelStation
+	}{
+		{
+			// Crosses the rounding boundary at 51.5015.
+			name: "moved a few metres",
+			seen: types.FuelStation{Name: "ASDA", Brand: "ASDA", Postcode: "SW1A1AA", Latitude: 51.5016, Longitude: -0.1415},
+			want: asda,
+		},
+		{
+			name: "renamed without a location",
+			seen: types.FuelStation{Name: "SHELL RECHARGE", Brand: "SHELL", Postcode: "M1 1AA"},
+			want: noLocation,
+		},
+		{
+			name: "different postcode",
+			seen: types.FuelStation{Name: "ASDA", Brand: "ASDA", Postcode: "SW1A 2AA", Latitude: 51.5016, Longitude: -0.1415},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := LoadRegistry(filepath.Join(t.TempDir(), "stations.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Observe([]types.FuelStation{asda, bp, noLocation}, at)
+			r.Observe([]types.FuelStation{tt.seen}, at.Add(time.Hour))
+
+			got := r.ID(StationID(tt.seen))
+			if tt.want.Postcode == "" {
+				if got != StationID(tt.seen) {
+					t.Errorf("got ID %s, want a new one", got)
+				}
+				return
+			}
+			if want := StationID(tt.want); got != want {
+				t.Errorf("got ID %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestRegistryKeepsNeighboursApart(t *testing.T) {
+	r, err := LoadRegistry(filepath.Join(t.TempDir(), "stations.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Two forecourts at the same services, seen for the first time together.
+	a := types.FuelStation{Name: "NORTHBOUND", Postcode: "AB1 2CD", Latitude: 52.1004, Longitude: -1.2}
+	b := types.FuelStation{Name: "SOUTHBOUND", Postcode: "AB1 2CD", Latitude: 52.1006, Longitude: -1.2}
+	r.Observe([]types.FuelStation{a, b}, time.Now())
+
+	if r.ID(StationID(a)) == r.ID(StationID(b)) {
+		t.Error("stations seen together were given the same ID")
+	}
+}
+
+func TestRegistryRecoversFromCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stations.json")
+	if err := os.WriteFile(path, []byte(`{"stations": {`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	r, err := LoadRegistry(path)
+	if err != nil {
+		t.Fatalf("got error %v, want a fresh registry", err)
+	}
+	stn := types.FuelStation{Name: "BP CONNECT", Brand: "BP", Postcode: "SW1A 1AA", Latitude: 51.5034, Longitude: -0.1415}
+	r.Observe([]types.FuelStation{stn}, time.Now())
+	if err := r.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err = LoadRegistry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.List()) != 1 {
+		t.Errorf("got %d stations after saving, want 1", len(r.List()))
+	}
+}
+
+func TestRegistryProvidersNameStationsDifferently(t *testing.T) {
+	r, err := LoadRegistry(filepath.Join(t.TempDir(), "stations.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Retail feeds name a station by its address, UKVD by its name.
+	retail := types.FuelStation{Name: "1 HIGH STREET", Brand: "ASDA", Postcode: "SW1A 1AA", Latitude: 51.5014, Longitude: -0.1415}
+	ukvd := retail
+	ukvd.Name = "ASDA SUPERSTORE"
+
+	at := time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		seen        types.FuelStation
+		wantRenamed bool
+	}{
+		{retail, false},
+		{ukvd, true},
+		{retail, false},
+		{ukvd, false},
+		{retail, false},
+	}
+	for i, tt := range tests {
+		seenAt := at.Add(time.Duration(i) * time.Hour)
+		renamed := r.Observe([]types.FuelStation{tt.seen}, seenAt)
+		if got := len(renamed) > 0; got != tt.wantRenamed {
+			t.Errorf("observation %d: renamed = %v, want %v", i, got, tt.wantRenamed)
+		}
+
+		known := r.List()
+		if len(known) != 1 {
+			t.Fatalf("observation %d: got %d stations, want 1", i, len(known))
+		}
+		cur := known[0].Current()
+		if cur.Name != tt.seen.Name || !cur.LastSeen.Equal(seenAt) {
+			t.Errorf("observation %d: current name is %q last seen %v, want %q at %v", i, cur.Name, cur.LastSeen, tt.seen.Name, seenAt)
+		}
+	}
+	if n := len(r.List()[0].Names); n != 2 {
+		t.Errorf("got %d names, want 2", n)
+	}
+}
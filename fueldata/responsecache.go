@@ -0,0 +1,103 @@
+package fueldata
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/poolski/fueltracker/cache"
+	"github.com/poolski/fueltracker/config"
+)
+
+const defaultCacheTTL = time.Hour
+
+// CacheMode controls how a provider uses its response cache.
+type CacheMode int
+
+const (
+	// CacheDefault serves cached responses younger than the TTL and caches
+	// anything fetched.
+	CacheDefault CacheMode = iota
+	// CacheRefresh always fetches, then updates the cache.
+	CacheRefresh
+	// CacheOff neither reads from nor writes to the cache.
+	CacheOff
+	// CacheOffline never touches the network and serves the last cached
+	// response, however old.
+	CacheOffline
+)
+
+// ResponseCache holds the cache settings of a provider which caches raw
+// responses.
+type ResponseCache struct {
+	Cache     *cache.Store
+	CacheTTL  time.Duration
+	CacheMode CacheMode
+}
+
+func (c *ResponseCache) configureCache(cfg config.CacheConfig) error {
+	c.CacheTTL = cfg.TTL
+	if c.CacheTTL == 0 {
+		c.CacheTTL = defaultCacheTTL
+	}
+
+	switch {
+	case cfg.Offline:
+		c.CacheMode = CacheOffline
+	case cfg.Disabled:
+		c.CacheMode = CacheOff
+		return nil
+	case cfg.Refresh:
+		c.CacheMode = CacheRefresh
+	}
+
+	dir := cfg.Dir
+	if dir == "" {
+		var err error
+		dir, err = cache.DefaultDir()
+		if err != nil {
+			return err
+		}
+	}
+	c.Cache = cache.New(dir)
+	return nil
+}
+
+// cached returns the response cached under key, and when it was fetched, if
+// the cache mode allows it to be used. ok is false if the response should be
+// fetched instead. In offline mode, a missing response is an error; what
+// describes it for the message.
+func (c *ResponseCache) cached(key, what string) (body []byte, at time.Time, ok bool, err error) {
+	if c.Cache == nil {
+		if c.CacheMode == CacheOffline {
+			return nil, time.Time{}, false, errors.New("offline mode needs the cache to be enabled")
+		}
+		return nil, time.Time{}, false, nil
+	}
+
+	switch c.CacheMode {
+	case CacheOffline:
+		body, at, err := c.Cache.Get(key)
+		if errors.Is(err, cache.ErrMiss) {
+			return nil, time.Time{}, false, fmt.Errorf("offline and no cached response for %s", what)
+		}
+		return body, at, err == nil, err
+	case CacheDefault:
+		body, at, err := c.Cache.Get(key)
+		if err == nil && time.Since(at) < c.CacheTTL {
+			return body, at, true, nil
+		}
+	}
+	return nil, time.Time{}, false, nil
+}
+
+// store caches a freshly fetched response under key, unless caching is off.
+func (c *ResponseCache) store(key string, body []byte) {
+	if c.Cache == nil || c.CacheMode == CacheOff {
+		return
+	}
+	if err := c.Cache.Put(key, body); err != nil {
+		log.Printf("caching response: %v", err)
+	}
+}
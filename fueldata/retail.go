@@ -0,0 +1,338 @@
+package fueldata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/poolski/fueltracker/config"
+	"github.com/poolski/fueltracker/geo"
+	"github.com/poolski/fueltracker/types"
+)
+
+const (
+	retailProvider       = "retail"
+	defaultRadiusMiles   = 5
+	defaultGeocoderURL   = "https://api.postcodes.io"
+	retailFeedTimeFormat = "02/01/2006 15:04:05"
+)
+
+func init() {
+	RegisterProvider(retailProvider, func(cfg *config.Config) (Provider, error) {
+		if len(cfg.Retail.Feeds) == 0 {
+			return nil, errors.New("retail provider requires at least one feed in retail.feeds")
+		}
+		r := NewRetail(cfg.Retail.Feeds)
+		r.Client = newHTTPClient(cfg.HTTP)
+		r.Retry = newRetryPolicy(cfg.HTTP)
+		if err := r.configureCache(cfg.Cache); err != nil {
+			return nil, err
+		}
+		if cfg.Retail.RadiusMiles > 0 {
+			r.RadiusMiles = cfg.Retail.RadiusMiles
+		}
+		if cfg.Retail.GeocoderURL != "" {
+			r.GeocoderURL = cfg.Retail.GeocoderURL
+		}
+		return r, nil
+	})
+}
+
+// Retail is a Provider which reads the open JSON price feeds published by
+// UK fuel retailers under the CMA fuel price transparency scheme. Feeds may
+// be http(s) URLs or paths to saved copies on disk. If Cache is set, feeds
+// fetched over http(s) and geocoded postcodes are cached like UKVD responses.
+type Retail struct {
+	ResponseCache
+
+	Feeds       []string
+	RadiusMiles int
+	GeocoderURL string
+	Client      *http.Client
+	Retry       RetryPolicy
+}
+
+func NewRetail(feeds []string) *Retail {
+	return &Retail{
+		Feeds:       feeds,
+		RadiusMiles: defaultRadiusMiles,
+		GeocoderURL: defaultGeocoderURL,
+		Client:      &http.Client{Timeout: defaultHTTPTimeout},
+		Retry:       DefaultRetryPolicy,
+	}
+}
+
+func (r *Retail) Name() string {
+	return retailProvider
+}
+
+// retailFeed is the document format shared by the retailer feeds.
+type retailFeed struct {
+	LastUpdated string          `json:"last_updated"`
+	Stations    []retailStation `json:"stations"`
+}
+
+type retailStation struct {
+	SiteID   string `json:"site_id"`
+	Brand    string `json:"brand"`
+	Address  string `json:"address"`
+	Postcode string `json:"postcode"`
+	Location struct {
+		Latitude  flexFloat `json:"latitude"`
+		Longitude flexFloat `json:"longitude"`
+	} `json:"location"`
+	Prices map[string]flexFloat `json:"prices"`
+}
+
+// flexFloat accepts both JSON numbers and numeric strings, as some retailers
+// publish coordinates and prices as strings.
+type flexFloat float64
+
+func (f *flexFloat) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if s == "" || s == "null" {
+		*f = 0
+		return nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("parsing %s as a number: %w", b, err)
+	}
+	*f = flexFloat(v)
+	return nil
+}
+
+func (r *Retail) Stations(ctx context.Context, opts QueryOpts) (*Response, error) {
+	lat, lon := opts.Latitude, opts.Longitude
+	if lat == 0 && lon == 0 {
+		var err error
+		lat, lon, err = r.geocode(ctx, opts.Postcode)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	radius := float64(r.RadiusMiles)
+	if opts.MaxDistance > 0 {
+		radius = opts.MaxDistance
+	}
+
+	var stations []types.FuelStation
+	loaded := 0
+	fetchedAt, fromCache := time.Now(), false
+	for _, src := range r.Feeds {
+		feed, at, cached, err := r.loadFeed(ctx, src)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			log.Printf("skipping feed %s: %v", src, err)
+			continue
+		}
+		loaded++
+		// The response is only as fresh as its oldest feed.
+		if cached {
+			fromCache = true
+			if at.Before(fetchedAt) {
+				fetchedAt = at
+			}
+		}
+
+		for _, rs := range feed.Stations {
+			stn := rs.normalize(feed.LastUpdated)
+			stn.DistanceFromSearchPostcode = geo.Distance(lat, lon, stn.Latitude, stn.Longitude)
+			if stn.DistanceFromSearchPostcode > radius {
+				continue
+			}
+			stations = append(stations, stn)
+		}
+	}
+	if loaded == 0 {
+		return nil, errors.New("none of the retail feeds could be loaded")
+	}
+
+	sort.Slice(stations, func(i, j int) bool {
+		return stations[i].DistanceFromSearchPostcode < stations[j].DistanceFromSearchPostcode
+	})
+
+	return &Response{
+		Provider:     r.Name(),
+		SearchRadius: radius,
+		Stations:     stations,
+		FetchedAt:    fetchedAt,
+		FromCache:    fromCache,
+	}, nil
+}
+
+// loadFeed reads the feed at src, and returns when it was fetched and
+// whether it came from the cache.
+func (r *Retail) loadFeed(ctx context.Context, src string) (*retailFeed, time.Time, bool, error) {
+	var body []byte
+	fetchedAt, fromCache := time.Now(), false
+	isURL := strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://")
+	if isURL {
+		b, at, ok, err := r.cached(r.feedCacheKey(src), src)
+		if err != nil {
+			return nil, time.Time{}, false, err
+		}
+		if ok {
+			body, fetchedAt, fromCache = b, at, true
+		} else {
+			// Some retailers reject requests without a browser-like user
+			// agent.
+			header := http.Header{}
+			header.Set("User-Agent", "Mozilla/5.0 (compatible; fueltracker)")
+			b, status, err := fetch(ctx, r.Client, r.Retry, src, header)
+			if err != nil {
+				return nil, time.Time{}, false, err
+			}
+			if status != http.StatusOK {
+				return nil, time.Time{}, false, fmt.Errorf("unexpected status %d", status)
+			}
+			body = b
+		}
+	} else {
+		b, err := os.ReadFile(src)
+		if err != nil {
+			return nil, time.Time{}, false, err
+		}
+		body = b
+	}
+
+	feed := &retailFeed{}
+	if err := json.Unmarshal(body, feed); err != nil {
+		return nil, time.Time{}, false, fmt.Errorf("%w: decoding feed: %v", ErrMalformedResponse, err)
+	}
+	if isURL && !fromCache {
+		r.store(r.feedCacheKey(src), body)
+	}
+	return feed, fetchedAt, fromCache, nil
+}
+
+func (r *Retail) feedCacheKey(src string) string {
+	return "retail-feed-" + src
+}
+
+// geocode resolves a postcode to coordinates using a postcodes.io compatible
+// API.
+func (r *Retail) geocode(ctx context.Context, postcode string) (float64, float64, error) {
+	if postcode == "" {
+		return 0, 0, errors.New("please specify a postcode or coordinates")
+	}
+
+	u, err := url.Parse(r.GeocoderURL)
+	if err != nil {
+		return 0, 0, err
+	}
+	u = u.JoinPath("postcodes", postcode)
+
+	// Cache the location as well as the feeds, so offline mode doesn't
+	// need the network at all.
+	key := "retail-geocode-" + normalizePostcode(postcode)
+	body, _, fromCache, err := r.cached(key, "postcode "+postcode)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !fromCache {
+		if body, _, err = fetch(ctx, r.Client, r.Retry, u.String(), nil); err != nil {
+			return 0, 0, fmt.Errorf("geocoding postcode: %w", err)
+		}
+	}
+
+	var data struct {
+		Status int    `json:"status"`
+		Error  string `json:"error"`
+		Result struct {
+			Latitude  float64 `json:"latitude"`
+			Longitude float64 `json:"longitude"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return 0, 0, fmt.Errorf("%w: decoding geocoder response: %v", ErrMalformedResponse, err)
+	}
+	if data.Status == http.StatusNotFound {
+		return 0, 0, &APIError{Provider: r.Name(), StatusMessage: data.Error, Kind: ErrInvalidPostcode}
+	}
+	if data.Status != http.StatusOK {
+		return 0, 0, fmt.Errorf("geocoding postcode %s: %s", postcode, data.Error)
+	}
+	if !fromCache {
+		r.store(key, body)
+	}
+	return data.Result.Latitude, data.Result.Longitude, nil
+}
+
+// normalize converts a feed station into the same shape the UKVD API
+// returns, so the rest of the package doesn't need to care where it came
+// from.
+func (rs retailStation) normalize(lastUpdated string) types.FuelStation {
+	stn := types.FuelStation{
+		Brand:     rs.Brand,
+		Name:      rs.Address,
+		Postcode:  rs.Postcode,
+		Latitude:  float64(rs.Location.Latitude),
+		Longitude: float64(rs.Location.Longitude),
+	}
+
+	recorded := lastUpdated
+	// Feeds are mostly in UK local time, but some use RFC 3339. We store
+	// timestamps in UTC like UKVD.
+	if t, err := time.ParseInLocation(retailFeedTimeFormat, lastUpdated, types.London); err == nil {
+		recorded = t.UTC().Format(ukvdTimeFormat)
+	} else if t, err := time.Parse(time.RFC3339, lastUpdated); err == nil {
+		recorded = t.UTC().Format(ukvdTimeFormat)
+	}
+
+	grades := make([]string, 0, len(rs.Prices))
+	for grade := range rs.Prices {
+		grades = append(grades, grade)
+	}
+	sort.Strings(grades)
+
+	for _, grade := range grades {
+		// Feeds use the grade labels on the pumps (E10, E5, B7, SDV).
+		ft, ok := LookupFuelType(grade)
+		if !ok || ft.Sells == nil || ft.Name == FuelTypeEV {
+			continue
+		}
+		fuelType := ft.Name
+		pence := float64(rs.Prices[grade])
+		if pence <= 0 {
+			continue
+		}
+		// A few feeds publish prices in pounds rather than pence.
+		if pence < 10 {
+			pence *= 100
+		}
+
+		fp := types.FuelPrice{FuelType: fuelType}
+		fp.LatestRecordedPrice.InPence = pence
+		fp.LatestRecordedPrice.InGbp = pence / 100
+		fp.LatestRecordedPrice.TimeRecorded = recorded
+		stn.FuelPriceList = append(stn.FuelPriceList, fp)
+		stn.FuelPriceCount++
+
+		switch fuelType {
+		case FuelTypeUnleaded:
+			stn.Features.Fuel.HasUnleaded = true
+		case FuelTypeSuperUnleaded:
+			stn.Features.Fuel.HasSuperUnleaded = true
+		case FuelTypeDiesel:
+			stn.Features.Fuel.HasDiesel = true
+		case FuelTypePremiumDiesel:
+			stn.Features.Fuel.HasPremiumDiesel = true
+		case FuelTypeLPG:
+			stn.Features.Fuel.HasLpg = true
+		}
+	}
+	return stn
+}
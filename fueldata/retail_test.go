@@ -0,0 +1,188 @@
+package fueldata
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/poolski/fueltracker/types"
+)
+
+// newTestRetail returns a Retail provider reading the named feeds from
+// testdata through a local server, which also geocodes SW1A 1AA.
+func newTestRetail(t *testing.T, feeds ...string) *Retail {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.Handle("/feeds/", http.StripPrefix("/feeds/", http.FileServer(http.Dir("testdata"))))
+	mux.HandleFunc("/postcodes/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path != "/postcodes/SW1A1AA" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"status":404,"error":"Postcode not found"}`))
+			return
+		}
+		w.Write([]byte(`{"status":200,"result":{"latitude":51.501,"longitude":-0.1416}}`))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	urls := make([]string, len(feeds))
+	for i, f := range feeds {
+		urls[i] = srv.URL + "/feeds/" + f
+	}
+	r := NewRetail(urls)
+	r.GeocoderURL = srv.URL
+	r.Client = srv.Client()
+	return r
+}
+
+// prices returns the price of each fuel type at stn.
+func prices(stn types.FuelStation) map[string]types.Price {
+	out := map[string]types.Price{}
+	for _, fp := range stn.FuelPriceList {
+		out[fp.FuelType] = types.PriceFromPence(fp.LatestRecordedPrice.InPence)
+	}
+	return out
+}
+
+func TestRetailStations(t *testing.T) {
+	r := newTestRetail(t, "asda.json", "bp.json")
+	res, err := r.Stations(context.Background(), QueryOpts{Postcode: "SW1A1AA"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Provider != retailProvider {
+		t.Errorf("provider = %q, want %q", res.Provider, retailProvider)
+	}
+	// The Manchester station is outside the default radius.
+	if len(res.Stations) != 2 {
+		t.Fatalf("got %d stations, want 2: %+v", len(res.Stations), res.Stations)
+	}
+
+	asda, bp := res.Stations[0], res.Stations[1]
+	if asda.Brand != "ASDA" || bp.Brand != "BP" {
+		t.Fatalf("stations = %s, %s, want ASDA then BP, nearest first", asda.Brand, bp.Brand)
+	}
+
+	tests := []struct {
+		name string
+		stn  types.FuelStation
+		want map[string]types.Price
+	}{
+		{
+			name: "number prices",
+			stn:  asda,
+			want: map[string]types.Price{
+				FuelTypeUnleaded:      types.PriceFromPence(139.7),
+				FuelTypeSuperUnleaded: types.PriceFromPence(151.7),
+				FuelTypeDiesel:        types.PriceFromPence(144.7),
+			},
+		},
+		{
+			// E10 is in pounds, which is converted to pence.
+			name: "string prices",
+			stn:  bp,
+			want: map[string]types.Price{
+				FuelTypeUnleaded:      types.PriceFromPence(145.9),
+				FuelTypeDiesel:        types.PriceFromPence(152.9),
+				FuelTypePremiumDiesel: types.PriceFromPence(169.9),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := prices(tt.stn)
+			if len(got) != len(tt.want) {
+				t.Errorf("got prices %v, want %v", got, tt.want)
+			}
+			for ft, want := range tt.want {
+				if got[ft] != want {
+					t.Errorf("%s = %s, want %s", ft, got[ft], want)
+				}
+				if !sellsFuel(tt.stn, ft) {
+					t.Errorf("station doesn't sell %s", ft)
+				}
+			}
+		})
+	}
+}
+
+func TestRetailRadius(t *testing.T) {
+	r := newTestRetail(t, "asda.json", "bp.json")
+	res, err := r.Stations(context.Background(), QueryOpts{Postcode: "SW1A1AA", MaxDistance: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.SearchRadius != 0.5 {
+		t.Errorf("search radius = %v, want 0.5", res.SearchRadius)
+	}
+	if len(res.Stations) != 1 || res.Stations[0].Brand != "ASDA" {
+		t.Errorf("got %+v, want just the ASDA station", res.Stations)
+	}
+	for _, stn := range res.Stations {
+		if stn.DistanceFromSearchPostcode > 0.5 {
+			t.Errorf("%s is %.2f miles away, outside the radius", stn.Name, stn.DistanceFromSearchPostcode)
+		}
+	}
+}
+
+func TestRetailSkipsBrokenFeeds(t *testing.T) {
+	r := newTestRetail(t, "missing.json", "bp.json")
+	res, err := r.Stations(context.Background(), QueryOpts{Postcode: "SW1A1AA"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Stations) != 1 || res.Stations[0].Brand != "BP" {
+		t.Errorf("got %+v, want just the BP station", res.Stations)
+	}
+
+	r = newTestRetail(t, "missing.json")
+	if _, err := r.Stations(context.Background(), QueryOpts{Postcode: "SW1A1AA"}); err == nil {
+		t.Error("got no error when no feeds could be loaded")
+	}
+}
+
+func TestRetailUnknownPostcode(t *testing.T) {
+	r := newTestRetail(t, "asda.json")
+	_, err := r.Stations(context.Background(), QueryOpts{Postcode: "ZZ991ZZ"})
+	if !errors.Is(err, ErrInvalidPostcode) {
+		t.Errorf("got error %v, want ErrInvalidPostcode", err)
+	}
+}
+
+func TestRetailFeedTimes(t *testing.T) {
+	r := newTestRetail(t, "asda.json", "tesco.json", "badtime.json")
+	c := NewWithProvider(r)
+	res, err := c.Lookup(context.Background(), QueryOpts{Postcode: "SW1A1AA", FuelType: FuelTypeUnleaded})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]time.Time{
+		// UK local time, which is BST in October.
+		"ASDA": time.Date(2026, 10, 17, 8, 30, 0, 0, time.UTC),
+		// RFC 3339.
+		"TESCO": time.Date(2026, 10, 17, 10, 15, 0, 0, time.UTC),
+		// Unreadable, but the price is still there.
+		"ESSO": {},
+	}
+	if len(res.Prices) != len(want) {
+		t.Fatalf("got %d prices, want %d: %+v", len(res.Prices), len(want), res.Prices)
+	}
+	for _, p := range res.Prices {
+		w, ok := want[p.Brand]
+		if !ok {
+			t.Errorf("unexpected price from %s", p.Brand)
+			continue
+		}
+		if !p.RecordedAt.Equal(w) {
+			t.Errorf("%s recorded at %v, want %v", p.Brand, p.RecordedAt, w)
+		}
+		if p.Price == 0 {
+			t.Errorf("%s has no price", p.Brand)
+		}
+	}
+}
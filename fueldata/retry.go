@@ -0,0 +1,211 @@
+package fueldata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/poolski/fueltracker/config"
+)
+
+const (
+	defaultHTTPTimeout   = 30 * time.Second
+	defaultRetries       = 3
+	defaultRetryDelay    = 500 * time.Millisecond
+	defaultMaxRetryDelay = 10 * time.Second
+)
+
+// RetryPolicy controls how transient HTTP failures are retried. Delays grow
+// exponentially from BaseDelay up to MaxDelay, with jitter.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultRetryPolicy is used by providers which haven't been given one.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: defaultRetries + 1,
+	BaseDelay:   defaultRetryDelay,
+	MaxDelay:    defaultMaxRetryDelay,
+}
+
+// Attempt records the outcome of a single HTTP request.
+type Attempt struct {
+	StatusCode int
+	Duration   time.Duration
+	Err        error
+}
+
+func (a Attempt) String() string {
+	if a.Err != nil {
+		return fmt.Sprintf("%v after %s", a.Err, a.Duration.Round(time.Millisecond))
+	}
+	return fmt.Sprintf("HTTP %d after %s", a.StatusCode, a.Duration.Round(time.Millisecond))
+}
+
+// RetryError is returned when a request still fails after every attempt.
+type RetryError struct {
+	URL      string
+	Attempts []Attempt
+}
+
+func (e *RetryError) Error() string {
+	history := make([]string, 0, len(e.Attempts))
+	for i, a := range e.Attempts {
+		history = append(history, fmt.Sprintf("#%d: %s", i+1, a))
+	}
+	return fmt.Sprintf("request to %s failed after %d attempts (%s)", e.URL, len(e.Attempts), strings.Join(history, "; "))
+}
+
+// Unwrap returns the error from the last attempt, if there was one.
+func (e *RetryError) Unwrap() error {
+	if len(e.Attempts) == 0 {
+		return nil
+	}
+	return e.Attempts[len(e.Attempts)-1].Err
+}
+
+// newHTTPClient returns a client with the timeout from cfg.
+func newHTTPClient(cfg config.HTTPConfig) *http.Client {
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = defaultHTTPTimeout
+	}
+	return &http.Client{Timeout: timeout}
+}
+
+// newRetryPolicy returns the retry policy described by cfg.
+func newRetryPolicy(cfg config.HTTPConfig) RetryPolicy {
+	p := DefaultRetryPolicy
+	if cfg.Retries > 0 {
+		p.MaxAttempts = cfg.Retries + 1
+	} else if cfg.Retries < 0 {
+		p.MaxAttempts = 1
+	}
+	if cfg.RetryDelay > 0 {
+		p.BaseDelay = cfg.RetryDelay
+	}
+	if cfg.MaxRetryDelay > 0 {
+		p.MaxDelay = cfg.MaxRetryDelay
+	}
+	return p
+}
+
+// fetch performs a GET request, retrying timeouts, 5xx and 429 responses
+// according to policy. It returns the body and status code of the first
+// response which isn't worth retrying.
+func fetch(ctx context.Context, client *http.Client, policy RetryPolicy, rawURL string, header http.Header) ([]byte, int, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+
+	retryErr := &RetryError{URL: redactURL(rawURL)}
+	for n := 1; ; n++ {
+		start := time.Now()
+		body, status, retryAfter, err := fetchOnce(ctx, client, rawURL, header)
+		attempt := Attempt{StatusCode: status, Duration: time.Since(start), Err: err}
+		if err == nil && !retryableStatus(status) {
+			return body, status, nil
+		}
+		retryErr.Attempts = append(retryErr.Attempts, attempt)
+
+		if ctx.Err() != nil || (err != nil && !retryableError(err)) || n >= policy.MaxAttempts {
+			return nil, status, retryErr
+		}
+
+		delay := policy.backoff(n)
+		if retryAfter > delay {
+			delay = retryAfter
+		}
+		if delay > policy.MaxDelay {
+			delay = policy.MaxDelay
+		}
+		select {
+		case <-ctx.Done():
+			return nil, status, retryErr
+		case <-time.After(delay):
+		}
+	}
+}
+
+func fetchOnce(ctx context.Context, client *http.Client, rawURL string, header http.Header) ([]byte, int, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		// The URL may contain an API key, so don't let it leak into errors.
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return nil, 0, 0, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, res.StatusCode, 0, err
+	}
+
+	var retryAfter time.Duration
+	if secs, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil {
+		retryAfter = time.Duration(secs) * time.Second
+	}
+	return body, res.StatusCode, retryAfter, nil
+}
+
+// backoff returns the delay before attempt n+1: exponential growth capped
+// at MaxDelay, with up to half of it randomised to spread out retries.
+func (p RetryPolicy) backoff(n int) time.Duration {
+	delay := p.BaseDelay << (n - 1)
+	if delay > p.MaxDelay || delay <= 0 {
+		delay = p.MaxDelay
+	}
+	half := int64(delay / 2)
+	if half <= 0 {
+		return delay
+	}
+	return time.Duration(half + rand.Int63n(half))
+}
+
+func retryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+func retryableError(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// redactURL strips the query string, which may contain API keys, so URLs are
+// safe to put in error messages.
+func redactURL(u string) string {
+	if i := strings.IndexByte(u, '?'); i >= 0 {
+		return u[:i]
+	}
+	return u
+}
@@ -0,0 +1,125 @@
+package fueldata
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fastRetries retries without waiting long, to keep the tests quick.
+var fastRetries = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+func TestFetch(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []int
+		retryAfter string
+		wantStatus int
+		wantErr    bool
+		wantCalls  int32
+	}{
+		{name: "ok", statuses: []int{200}, wantStatus: 200, wantCalls: 1},
+		{name: "retries a server error", statuses: []int{503, 200}, wantStatus: 200, wantCalls: 2},
+		{name: "retries too many requests", statuses: []int{429, 429, 200}, retryAfter: "1", wantStatus: 200, wantCalls: 3},
+		{name: "gives up after every attempt", statuses: []int{500, 502, 503, 200}, wantStatus: 503, wantErr: true, wantCalls: 3},
+		{name: "doesn't retry a client error", statuses: []int{404, 200}, wantStatus: 404, wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := atomic.AddInt32(&calls, 1)
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(tt.statuses[n-1])
+				w.Write([]byte("body"))
+			}))
+			defer srv.Close()
+
+			body, status, err := fetch(context.Background(), srv.Client(), fastRetries, srv.URL+"?key=secret", nil)
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+			if got := atomic.LoadInt32(&calls); got != tt.wantCalls {
+				t.Errorf("made %d requests, want %d", got, tt.wantCalls)
+			}
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("got error %v", err)
+				}
+				if string(body) != "body" {
+					t.Errorf("body = %q, want %q", body, "body")
+				}
+				return
+			}
+
+			var retryErr *RetryError
+			if !errors.As(err, &retryErr) {
+				t.Fatalf("got error %v, want a RetryError", err)
+			}
+			if len(retryErr.Attempts) != int(tt.wantCalls) {
+				t.Errorf("error has %d attempts, want %d", len(retryErr.Attempts), tt.wantCalls)
+			}
+			if strings.Contains(err.Error(), "secret") {
+				t.Errorf("error %q contains the query string", err)
+			}
+		})
+	}
+}
+
+func TestFetchNonRetryableError(t *testing.T) {
+	_, _, err := fetch(context.Background(), nil, fastRetries, "bogus://example.com", nil)
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) {
+		t.Fatalf("got error %v, want a RetryError", err)
+	}
+	if len(retryErr.Attempts) != 1 {
+		t.Errorf("made %d attempts, want 1", len(retryErr.Attempts))
+	}
+}
+
+func TestFetchCancelled(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	policy := RetryPolicy{MaxAttempts: 5, BaseDelay: time.Hour, MaxDelay: time.Hour}
+	if _, _, err := fetch(ctx, srv.Client(), policy, srv.URL, nil); err == nil {
+		t.Fatal("got no error from a cancelled fetch")
+	}
+	if got := atomic.LoadInt32(&calls); got > 1 {
+		t.Errorf("made %d requests after being cancelled", got)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	tests := []struct {
+		n    int
+		full time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, time.Second},
+		{64, time.Second},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if got := p.backoff(tt.n); got < tt.full/2 || got >= tt.full {
+				t.Errorf("backoff(%d) = %s, want between %s and %s", tt.n, got, tt.full/2, tt.full)
+			}
+		}
+	}
+}
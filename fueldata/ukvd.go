@@ -0,0 +1,163 @@
+package fueldata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/poolski/fueltracker/config"
+	"github.com/poolski/fueltracker/types"
+)
+
+const (
+	fuelPriceEndpoint = "api/datapackage/FuelPriceData"
+	ukvdAPIVersion    = "2"
+	// ukvdTimeFormat is how UKVD reports when a price was recorded, in UTC.
+	// Other providers convert their times to it.
+	ukvdTimeFormat = "1/2/2006 3:04:05 PM"
+)
+
+func init() {
+	RegisterProvider(DefaultProvider, func(cfg *config.Config) (Provider, error) {
+		if cfg.UKVDAPIKey == "" {
+			return nil, errors.New("ukvd provider requires ukvd_api_key to be set")
+		}
+		u := NewUKVD(cfg.UKVDAPIKey)
+		u.Client = newHTTPClient(cfg.HTTP)
+		u.Retry = newRetryPolicy(cfg.HTTP)
+		if err := u.configureCache(cfg.Cache); err != nil {
+			return nil, err
+		}
+		return u, nil
+	})
+}
+
+// UKVD is a Provider backed by the UK Vehicle Data FuelPriceData API.
+// If Cache is set, raw responses are stored in it and reused for CacheTTL so
+// repeated lookups don't spend API credits.
+type UKVD struct {
+	ResponseCache
+
+	APIKey  string
+	BaseURL string
+	Client  *http.Client
+	Retry   RetryPolicy
+}
+
+func NewUKVD(apiKey string) *UKVD {
+	return &UKVD{
+		APIKey:  apiKey,
+		BaseURL: "https://uk1.ukvehicledata.co.uk",
+		Client:  &http.Client{Timeout: defaultHTTPTimeout},
+		Retry:   DefaultRetryPolicy,
+	}
+}
+
+func (u *UKVD) Name() string {
+	return DefaultProvider
+}
+
+// CacheKey returns the key a postcode's raw response is cached under.
+func (u *UKVD) CacheKey(postcode string) string {
+	return fmt.Sprintf("ukvd-v%s-%s", ukvdAPIVersion, normalizePostcode(postcode))
+}
+
+func (u *UKVD) Stations(ctx context.Context, opts QueryOpts) (*Response, error) {
+	if opts.Postcode == "" {
+		return nil, errors.New("ukvd provider can only search by postcode")
+	}
+
+	body, fetchedAt, fromCache, err := u.responseBody(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	fd, err := parseUKVDResponse(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if !fromCache {
+		u.store(u.CacheKey(opts.Postcode), body)
+	}
+
+	return &Response{
+		Provider:     u.Name(),
+		SearchRadius: float64(fd.DataItems.FuelStationDetails.SearchRadiusUsed),
+		Stations:     fd.DataItems.FuelStationDetails.FuelStationList,
+		FetchedAt:    fetchedAt,
+		FromCache:    fromCache,
+	}, nil
+}
+
+// responseBody returns the raw response for a query, from the cache if the
+// cache mode allows it.
+func (u *UKVD) responseBody(ctx context.Context, opts QueryOpts) ([]byte, time.Time, bool, error) {
+	body, at, ok, err := u.cached(u.CacheKey(opts.Postcode), opts.Postcode)
+	if ok || err != nil {
+		return body, at, ok, err
+	}
+	body, err = u.doAPICall(ctx, opts)
+	return body, time.Now(), false, err
+}
+
+func (u *UKVD) doAPICall(ctx context.Context, opts QueryOpts) ([]byte, error) {
+	endpoint, err := url.Parse(u.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint.Path = fuelPriceEndpoint
+
+	q := endpoint.Query()
+	q.Set("v", ukvdAPIVersion)
+	q.Set("api_nullitems", "1")
+	q.Set("auth_apikey", u.APIKey)
+	q.Set("key_POSTCODE", strings.ToUpper(opts.Postcode))
+
+	endpoint.RawQuery = q.Encode()
+
+	body, _, err := fetch(ctx, u.Client, u.Retry, endpoint.String(), nil)
+	return body, err
+}
+
+func parseUKVDResponse(body []byte) (*types.FuelDataResponse, error) {
+	data := types.RawAPIResponse{}
+
+	err := json.Unmarshal(body, &data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedResponse, err)
+	}
+
+	res := &data.Response
+	switch res.StatusCode {
+	case "":
+		return nil, fmt.Errorf("%w: no status code", ErrMalformedResponse)
+	case "Success", "SuccessWithResultsBlockWarnings":
+	default:
+		return nil, &APIError{
+			Provider:      DefaultProvider,
+			StatusCode:    res.StatusCode,
+			StatusMessage: res.StatusMessage,
+			Kind:          classifyUKVDStatus(res.StatusCode),
+		}
+	}
+
+	// The request can succeed while the lookup itself fails, e.g. because
+	// the postcode doesn't exist.
+	lookup := res.StatusInformation.Lookup
+	if lookup.StatusCode != "" && lookup.StatusCode != "Success" {
+		return nil, &APIError{
+			Provider:      DefaultProvider,
+			StatusCode:    lookup.StatusCode,
+			StatusMessage: lookup.StatusMessage,
+			Kind:          classifyUKVDStatus(lookup.StatusCode),
+		}
+	}
+	return res, nil
+}
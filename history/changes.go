@@ -0,0 +1,213 @@
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/poolski/fueltracker/cache"
+	"github.com/poolski/fueltracker/types"
+)
+
+// Snapshot is every station and fuel price in one lookup, kept so the next
+// lookup of the same area can be compared with it.
+type Snapshot struct {
+	TakenAt  time.Time         `json:"taken_at"`
+	Stations []SnapshotStation `json:"stations"`
+}
+
+// SnapshotStation is a station in a Snapshot.
+type SnapshotStation struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Brand    string `json:"brand"`
+	Postcode string `json:"postcode"`
+	// Prices has an entry for every fuel the station sells. The price is
+	// zero if the station hasn't reported one.
+	Prices map[string]types.Price `json:"prices"`
+}
+
+// Snapshots keeps the latest two Snapshots of each area, one file per area.
+type Snapshots struct {
+	store *cache.Store
+
+	mu sync.Mutex
+}
+
+// snapshotFile is what's kept for each area.
+type snapshotFile struct {
+	Current  *Snapshot `json:"current"`
+	Previous *Snapshot `json:"previous,omitempty"`
+}
+
+// SnapshotsFor returns the snapshots kept alongside the history at path.
+func SnapshotsFor(path string) *Snapshots {
+	return &Snapshots{store: cache.New(filepath.Join(filepath.Dir(path), "snapshots"))}
+}
+
+func (s *Snapshots) load(key string) (*snapshotFile, error) {
+	f := &snapshotFile{}
+	data, _, err := s.store.Get(key)
+	if errors.Is(err, cache.ErrMiss) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("reading snapshot %s: %w", s.store.Path(key), err)
+	}
+	return f, nil
+}
+
+// Record stores snap as the latest snapshot under key and returns the one
+// before it, or nil if there isn't one. Recording the same data twice, e.g.
+// from the cache, leaves the snapshots alone, so the data is still compared
+// with the snapshot before it.
+func (s *Snapshots) Record(key string, snap *Snapshot) (*Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := s.load(key)
+	if err != nil {
+		return nil, err
+	}
+	if f.Current != nil && !snap.TakenAt.After(f.Current.TakenAt) {
+		return f.Previous, nil
+	}
+	f.Previous, f.Current = f.Current, snap
+
+	data, err := json.Marshal(f)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.Put(key, data); err != nil {
+		return nil, err
+	}
+	return f.Previous, nil
+}
+
+// ChangeKind is a kind of Change.
+type ChangeKind string
+
+const (
+	Rise ChangeKind = "rise"
+	Drop ChangeKind = "drop"
+	// NewStation is a station which wasn't in the previous snapshot.
+	NewStation ChangeKind = "new_station"
+	// GoneStation is a station which is no longer in the snapshot.
+	GoneStation ChangeKind = "gone_station"
+	// FuelGone is a fuel which a station no longer sells.
+	FuelGone ChangeKind = "fuel_gone"
+)
+
+// Change is a difference between two snapshots. FuelType, Was and Now are
+// only set for the kinds they make sense for.
+type Change struct {
+	Kind      ChangeKind
+	StationID string
+	Station   string
+	Brand     string
+	FuelType  string
+	Was       types.Price
+	Now       types.Price
+}
+
+// Delta returns how much the price changed.
+func (c Change) Delta() types.Price {
+	return c.Now - c.Was
+}
+
+// CompareOpts controls which changes Compare reports.
+type CompareOpts struct {
+	// MinRise and MinDrop, in pence, are the smallest rise and drop worth
+	// reporting. Zero reports any change.
+	MinRise float64
+	MinDrop float64
+	// FuelType, if set, only reports changes to that fuel, and stations
+	// which sell it.
+	FuelType string
+}
+
+// Compare returns the differences between prev and cur, with stations which
+// appeared or disappeared first, then price changes by station name.
+func Compare(prev, cur *Snapshot, opts CompareOpts) []Change {
+	before := map[string]SnapshotStation{}
+	for _, stn := range prev.Stations {
+		before[stn.ID] = stn
+	}
+	after := map[string]SnapshotStation{}
+	for _, stn := range cur.Stations {
+		after[stn.ID] = stn
+	}
+	sells := func(stn SnapshotStation) bool {
+		_, ok := stn.Prices[opts.FuelType]
+		return opts.FuelType == "" || ok
+	}
+	change := func(kind ChangeKind, stn SnapshotStation) Change {
+		return Change{Kind: kind, StationID: stn.ID, Station: stn.Name, Brand: stn.Brand}
+	}
+
+	var stations, prices []Change
+	for _, stn := range cur.Stations {
+		old, ok := before[stn.ID]
+		if !ok {
+			if sells(stn) {
+				stations = append(stations, change(NewStation, stn))
+			}
+			continue
+		}
+
+		for ft, was := range old.Prices {
+			if opts.FuelType != "" && ft != opts.FuelType {
+				continue
+			}
+			now, ok := stn.Prices[ft]
+			if !ok {
+				c := change(FuelGone, stn)
+				c.FuelType, c.Was = ft, was
+				prices = append(prices, c)
+				continue
+			}
+			// There's nothing to compare if either price is missing.
+			if was == 0 || now == 0 || was == now {
+				continue
+			}
+			c := change(Rise, stn)
+			c.FuelType, c.Was, c.Now = ft, was, now
+			if now < was {
+				c.Kind = Drop
+				if (was - now).Pence() < opts.MinDrop {
+					continue
+				}
+			} else if (now - was).Pence() < opts.MinRise {
+				continue
+			}
+			prices = append(prices, c)
+		}
+	}
+	for _, stn := range prev.Stations {
+		if _, ok := after[stn.ID]; !ok && sells(stn) {
+			stations = append(stations, change(GoneStation, stn))
+		}
+	}
+
+	sort.SliceStable(stations, func(i, j int) bool {
+		if stations[i].Kind != stations[j].Kind {
+			return stations[i].Kind == NewStation
+		}
+		return stations[i].Station < stations[j].Station
+	})
+	sort.SliceStable(prices, func(i, j int) bool {
+		a, b := prices[i], prices[j]
+		if a.Station != b.Station {
+			return a.Station < b.Station
+		}
+		return a.FuelType < b.FuelType
+	})
+	return append(stations, prices...)
+}
@@ -0,0 +1,93 @@
+package history
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/poolski/fueltracker/types"
+)
+
+func TestCompare(t *testing.T) {
+	stn := func(id string, prices map[string]types.Price) SnapshotStation {
+		return SnapshotStation{ID: id, Name: id, Brand: "BP", Prices: prices}
+	}
+	prev := &Snapshot{Stations: []SnapshotStation{
+		stn("ALPHA", map[string]types.Price{"E10": 1400, "B7": 1500, "SDV": 1600}),
+		stn("BETA", map[string]types.Price{"E10": 1450}),
+		stn("GONE", map[string]types.Price{"E10": 1390}),
+		stn("GONE DIESEL", map[string]types.Price{"B7": 1550}),
+	}}
+	cur := &Snapshot{Stations: []SnapshotStation{
+		stn("NEW", map[string]types.Price{"E10": 1380}),
+		// Up 2p, down 0.5p, and no longer selling super diesel.
+		stn("ALPHA", map[string]types.Price{"E10": 1420, "B7": 1495}),
+		// Not reporting a price any more, which isn't a change.
+		stn("BETA", map[string]types.Price{"E10": 0}),
+		stn("NEW DIESEL", map[string]types.Price{"B7": 1500}),
+	}}
+
+	tests := []struct {
+		name string
+		opts CompareOpts
+		want []string
+	}{
+		{
+			name: "everything",
+			want: []string{
+				"new_station NEW",
+				"new_station NEW DIESEL",
+				"gone_station GONE",
+				"gone_station GONE DIESEL",
+				"drop ALPHA B7 150.0p->149.5p",
+				"rise ALPHA E10 140.0p->142.0p",
+				"fuel_gone ALPHA SDV 160.0p->0.0p",
+			},
+		},
+		{
+			name: "one fuel",
+			opts: CompareOpts{FuelType: "E10"},
+			want: []string{
+				"new_station NEW",
+				"gone_station GONE",
+				"rise ALPHA E10 140.0p->142.0p",
+			},
+		},
+		{
+			name: "big changes only",
+			opts: CompareOpts{MinRise: 2.5, MinDrop: 0.5},
+			want: []string{
+				"new_station NEW",
+				"new_station NEW DIESEL",
+				"gone_station GONE",
+				"gone_station GONE DIESEL",
+				"drop ALPHA B7 150.0p->149.5p",
+				"fuel_gone ALPHA SDV 160.0p->0.0p",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, c := range Compare(prev, cur, tt.opts) {
+				s := fmt.Sprintf("%s %s", c.Kind, c.Station)
+				if c.FuelType != "" {
+					s += fmt.Sprintf(" %s %s->%s", c.FuelType, c.Was, c.Now)
+				}
+				got = append(got, s)
+			}
+			if g, w := strings.Join(got, "\n"), strings.Join(tt.want, "\n"); g != w {
+				t.Errorf("got changes:\n%s\nwant:\n%s", g, w)
+			}
+		})
+	}
+}
+
+func TestCompareUnchanged(t *testing.T) {
+	snap := &Snapshot{Stations: []SnapshotStation{
+		{ID: "a", Name: "ALPHA", Prices: map[string]types.Price{"E10": 1400}},
+	}}
+	if changes := Compare(snap, snap, CompareOpts{}); len(changes) != 0 {
+		t.Errorf("got changes %+v comparing a snapshot with itself", changes)
+	}
+}
@@ -0,0 +1,196 @@
+// Package history keeps every fuel price fueltracker has seen in a local
+// file, so trends can be worked out without a spreadsheet.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/poolski/fueltracker/config"
+	"github.com/poolski/fueltracker/types"
+)
+
+// Entry is a price for one fuel at one station, as reported at RecordedAt.
+type Entry struct {
+	StationID string `json:"station_id"`
+	Station   string `json:"station"`
+	Brand     string `json:"brand"`
+	Postcode  string `json:"postcode"`
+	FuelType  string `json:"fuel_type"`
+	// Price is in tenths of a penny per litre, as types.Price.
+	Price types.Price `json:"price"`
+	// RecordedAt is when the station reported the price.
+	RecordedAt time.Time `json:"recorded_at"`
+	// ObservedAt is when fueltracker first saw the price.
+	ObservedAt time.Time `json:"observed_at"`
+	Latitude   float64   `json:"latitude"`
+	Longitude  float64   `json:"longitude"`
+}
+
+// NewEntry returns the entry for r, first seen at observedAt.
+func NewEntry(r *types.SpecificFuelPrice, observedAt time.Time) Entry {
+	return Entry{
+		StationID:  r.StationID,
+		Station:    r.Station,
+		Brand:      r.Brand,
+		Postcode:   r.Postcode,
+		FuelType:   r.FuelType,
+		Price:      r.Price,
+		RecordedAt: r.RecordedAt.UTC(),
+		ObservedAt: observedAt.UTC().Truncate(time.Second),
+		Latitude:   r.Latitude,
+		Longitude:  r.Longitude,
+	}
+}
+
+// key identifies a price report. The same report turns up in every lookup
+// until the station reports a new price, so it is only stored once.
+func (e Entry) key() string {
+	return fmt.Sprintf("%s|%s|%d", e.StationID, e.FuelType, e.RecordedAt.Unix())
+}
+
+// Store is a history file. Entries are stored one JSON object per line and
+// only ever appended, so the file stays readable if fueltracker is killed
+// part way through a write.
+type Store struct {
+	path string
+
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+// DefaultPath returns where the history is kept if the config doesn't say
+// otherwise.
+func DefaultPath() (string, error) {
+	dir, err := config.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "history.jsonl"), nil
+}
+
+// Open returns the history stored at path. The file is created when the
+// first entry is added.
+func Open(path string) *Store {
+	return &Store{path: path}
+}
+
+// Path returns the file the history is stored in.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Add stores the entries which aren't already in the history, and returns
+// how many that was. Entries without a price or a time are skipped. It is
+// safe to call from several lookups at once.
+func (s *Store) Add(entries []Entry) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.seen == nil {
+		seen := map[string]bool{}
+		err := s.each(func(e Entry) {
+			seen[e.key()] = true
+		})
+		if err != nil {
+			return 0, err
+		}
+		s.seen = seen
+	}
+
+	var lines []byte
+	var keys []string
+	for _, e := range entries {
+		if e.StationID == "" || e.Price == 0 || e.RecordedAt.IsZero() {
+			continue
+		}
+		k := e.key()
+		if s.seen[k] {
+			continue
+		}
+		data, err := json.Marshal(e)
+		if err != nil {
+			return 0, err
+		}
+		lines = append(append(lines, data...), '\n')
+		keys = append(keys, k)
+		// Guard against duplicates within entries, too.
+		s.seen[k] = true
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return 0, err
+	}
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return 0, err
+	}
+	// If a crash left a partial line, start on a new one so only that
+	// line is lost.
+	if info, err := f.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			lines = append([]byte{'\n'}, lines...)
+		}
+	}
+	// Write everything at once so another process appending at the same
+	// time can't interleave with us.
+	if _, err := f.Write(lines); err != nil {
+		f.Close()
+		for _, k := range keys {
+			delete(s.seen, k)
+		}
+		return 0, err
+	}
+	return len(keys), f.Close()
+}
+
+// Entries returns every entry in the history, oldest first by when they
+// were added.
+func (s *Store) Entries() ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []Entry
+	err := s.each(func(e Entry) {
+		out = append(out, e)
+	})
+	return out, err
+}
+
+// each calls fn for every entry in the file. A missing file is an empty
+// history. Lines which can't be read, e.g. a partial line left by a crash,
+// are skipped.
+func (s *Store) each(fn func(Entry)) error {
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		var e Entry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			continue
+		}
+		fn(e)
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("reading history %s: %w", s.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+package history
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/poolski/fueltracker/postcode"
+	"github.com/poolski/fueltracker/types"
+)
+
+// Filter picks entries out of the history. Fields which are set must all
+// match.
+type Filter struct {
+	// Station matches any part of a station's name, ignoring case. If
+	// StationIDs is also set, an entry matching either passes.
+	Station    string
+	StationIDs []string
+	Brand      string
+	FuelType   string
+	Since      time.Time
+}
+
+// Match reports whether e passes the filter.
+func (f Filter) Match(e Entry) bool {
+	if f.Station != "" || len(f.StationIDs) > 0 {
+		found := f.Station != "" && strings.Contains(strings.ToUpper(e.Station), strings.ToUpper(strings.TrimSpace(f.Station)))
+		for _, id := range f.StationIDs {
+			if strings.EqualFold(id, e.StationID) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.Brand != "" && !strings.EqualFold(strings.TrimSpace(f.Brand), strings.TrimSpace(e.Brand)) {
+		return false
+	}
+	if f.FuelType != "" && f.FuelType != e.FuelType {
+		return false
+	}
+	return f.Since.IsZero() || !e.RecordedAt.Before(f.Since)
+}
+
+// Apply returns the entries which pass the filter.
+func (f Filter) Apply(entries []Entry) []Entry {
+	var out []Entry
+	for _, e := range entries {
+		if f.Match(e) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// ParseSince parses how far back to look, either as a duration before now
+// such as "90d", "2w" or "12h", or as a date such as "2026-01-31".
+func ParseSince(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, types.London); err == nil {
+		return t, nil
+	}
+	units := map[byte]time.Duration{'h': time.Hour, 'd': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if unit, ok := units[s[len(s)-1]]; ok {
+		if n, err := strconv.Atoi(s[:len(s)-1]); err == nil && n >= 0 {
+			return now.Add(-time.Duration(n) * unit), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("can't understand %q, use a number of hours, days or weeks such as '90d', or a date such as '2026-01-31'", s)
+}
+
+// GroupBy is how entries are grouped for statistics.
+type GroupBy string
+
+const (
+	GroupByStation GroupBy = "station"
+	GroupByBrand   GroupBy = "brand"
+	// GroupByOutward groups by outward postcode, e.g. "SW1A", which is
+	// roughly a town or district.
+	GroupByOutward GroupBy = "outward"
+	GroupByMonth   GroupBy = "month"
+)
+
+var groupBys = []GroupBy{GroupByStation, GroupByBrand, GroupByOutward, GroupByMonth}
+
+// ParseGroupBy returns the GroupBy named by s.
+func ParseGroupBy(s string) (GroupBy, error) {
+	for _, g := range groupBys {
+		if strings.EqualFold(s, string(g)) {
+			return g, nil
+		}
+	}
+	names := make([]string, len(groupBys))
+	for i, g := range groupBys {
+		names[i] = string(g)
+	}
+	return "", fmt.Errorf("unknown grouping %q, valid groupings are: %s", s, strings.Join(names, ", "))
+}
+
+// DailyPrice is the average price in a group on one day.
+type DailyPrice struct {
+	Date  time.Time
+	Pence float64
+}
+
+// Stats summarises the prices in a group. Prices are in pence per litre.
+type Stats struct {
+	Count  int
+	Min    float64
+	Max    float64
+	Mean   float64
+	Median float64
+	// Latest is the average price on the most recent day in the group.
+	Latest float64
+	// WeekChange is Latest less the price a week before that, or nil if
+	// there's no price from then.
+	WeekChange *float64
+}
+
+// Group is the entries for one fuel in a group, with their statistics.
+type Group struct {
+	// Key identifies the group, e.g. a station ID or a month.
+	Key string
+	// Label is the group's name for showing to people.
+	Label    string
+	FuelType string
+	// Entries are ordered by when they were recorded.
+	Entries []Entry
+	// Daily is the average price on each day with a price.
+	Daily []DailyPrice
+	Stats Stats
+}
+
+// Summarize groups entries and works out statistics for each group. Each
+// fuel is kept in a separate group. Groups are ordered by label, or by date
+// for GroupByMonth.
+func Summarize(entries []Entry, by GroupBy) []*Group {
+	index := map[string]*Group{}
+	var groups []*Group
+	for _, e := range entries {
+		key, label := groupKey(e, by)
+		id := key + "|" + e.FuelType
+		g, ok := index[id]
+		if !ok {
+			g = &Group{Key: key, Label: label, FuelType: e.FuelType}
+			index[id] = g
+			groups = append(groups, g)
+		}
+		g.Entries = append(g.Entries, e)
+	}
+
+	for _, g := range groups {
+		sort.SliceStable(g.Entries, func(i, j int) bool {
+			return g.Entries[i].RecordedAt.Before(g.Entries[j].RecordedAt)
+		})
+		// Stations are labelled with the name they have now.
+		if by == GroupByStation {
+			g.Label = g.Entries[len(g.Entries)-1].Station
+		}
+		g.Daily = daily(g.Entries)
+		g.Stats = stats(g.Entries, g.Daily)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		a, b := groups[i], groups[j]
+		if a.Key != b.Key {
+			if by == GroupByMonth {
+				return a.Key < b.Key
+			}
+			if a.Label != b.Label {
+				return a.Label < b.Label
+			}
+			return a.Key < b.Key
+		}
+		return a.FuelType < b.FuelType
+	})
+	return groups
+}
+
+func groupKey(e Entry, by GroupBy) (key, label string) {
+	switch by {
+	case GroupByBrand:
+		return strings.ToUpper(strings.TrimSpace(e.Brand)), e.Brand
+	case GroupByOutward:
+		if pc, err := postcode.Parse(e.Postcode); err == nil {
+			return pc.Outward, pc.Outward
+		}
+		return "?", "Unknown"
+	case GroupByMonth:
+		t := e.RecordedAt.In(types.London)
+		return t.Format("2006-01"), t.Format("Jan 2006")
+	}
+	return e.StationID, e.Station
+}
+
+// daily averages entries, which must be in order, by day in the UK.
+func daily(entries []Entry) []DailyPrice {
+	var out []DailyPrice
+	var sum float64
+	var n int
+	for i, e := range entries {
+		t := e.RecordedAt.In(types.London)
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, types.London)
+		sum += e.Price.Pence()
+		n++
+		if i+1 < len(entries) {
+			next := entries[i+1].RecordedAt.In(types.London)
+			if next.Year() == t.Year() && next.YearDay() == t.YearDay() {
+				continue
+			}
+		}
+		out = append(out, DailyPrice{Date: day, Pence: roundTenth(sum / float64(n))})
+		sum, n = 0, 0
+	}
+	return out
+}
+
+func stats(entries []Entry, days []DailyPrice) Stats {
+	prices := make([]float64, len(entries))
+	var sum float64
+	for i, e := range entries {
+		prices[i] = e.Price.Pence()
+		sum += prices[i]
+	}
+	sort.Float64s(prices)
+
+	s := Stats{
+		Count: len(prices),
+		Min:   prices[0],
+		Max:   prices[len(prices)-1],
+		Mean:  roundTenth(sum / float64(len(prices))),
+	}
+	if mid := len(prices) / 2; len(prices)%2 == 1 {
+		s.Median = prices[mid]
+	} else {
+		s.Median = roundTenth((prices[mid-1] + prices[mid]) / 2)
+	}
+
+	s.Latest = days[len(days)-1].Pence
+	weekAgo := days[len(days)-1].Date.AddDate(0, 0, -7)
+	for i := len(days) - 1; i >= 0; i-- {
+		if !days[i].Date.After(weekAgo) {
+			change := roundTenth(s.Latest - days[i].Pence)
+			s.WeekChange = &change
+			break
+		}
+	}
+	return s
+}
+
+// roundTenth rounds to the nearest tenth of a penny, which is as precise as
+// prices are reported.
+func roundTenth(pence float64) float64 {
+	return float64(types.PriceFromPence(pence)) / 10
+}
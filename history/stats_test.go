@@ -0,0 +1,104 @@
+package history
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/poolski/fueltracker/types"
+)
+
+func entry(id, station, fuel string, pence float64, at time.Time) Entry {
+	return Entry{StationID: id, Station: station, Brand: "BP", Postcode: "SW1A 1AA", FuelType: fuel, Price: types.PriceFromPence(pence), RecordedAt: at}
+}
+
+func TestSummarize(t *testing.T) {
+	day := func(d, h, m int) time.Time { return time.Date(2026, 10, d, h, m, 0, 0, time.UTC) }
+	entries := []Entry{
+		entry("a", "ALPHA", "E10", 143.9, day(8, 10, 0)),
+		entry("a", "OLD NAME", "E10", 140.0, day(1, 9, 0)),
+		entry("a", "OLD NAME", "E10", 141.0, day(1, 17, 0)),
+		// Half past midnight on the 5th in the UK.
+		entry("a", "OLD NAME", "E10", 142.0, day(4, 23, 30)),
+		entry("a", "ALPHA", "B7", 150.9, day(8, 10, 0)),
+		entry("b", "BETA", "E10", 139.9, day(8, 10, 0)),
+	}
+	groups := Summarize(entries, GroupByStation)
+
+	var labels []string
+	for _, g := range groups {
+		labels = append(labels, g.Label+" "+g.FuelType)
+	}
+	// Stations are labelled with their latest name.
+	if got, want := strings.Join(labels, ", "), "ALPHA B7, ALPHA E10, BETA E10"; got != want {
+		t.Fatalf("got groups %s, want %s", got, want)
+	}
+
+	g := groups[1]
+	wantDaily := []DailyPrice{
+		{Date: time.Date(2026, 10, 1, 0, 0, 0, 0, types.London), Pence: 140.5},
+		{Date: time.Date(2026, 10, 5, 0, 0, 0, 0, types.London), Pence: 142.0},
+		{Date: time.Date(2026, 10, 8, 0, 0, 0, 0, types.London), Pence: 143.9},
+	}
+	if len(g.Daily) != len(wantDaily) {
+		t.Fatalf("got daily prices %+v, want %+v", g.Daily, wantDaily)
+	}
+	for i, w := range wantDaily {
+		if !g.Daily[i].Date.Equal(w.Date) || g.Daily[i].Pence != w.Pence {
+			t.Errorf("day %d = %v %.1f, want %v %.1f", i, g.Daily[i].Date, g.Daily[i].Pence, w.Date, w.Pence)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		got, want float64
+	}{
+		{"count", float64(g.Stats.Count), 4},
+		{"min", g.Stats.Min, 140.0},
+		{"max", g.Stats.Max, 143.9},
+		// 141.725, to the nearest tenth.
+		{"mean", g.Stats.Mean, 141.7},
+		{"median", g.Stats.Median, 141.5},
+		{"latest", g.Stats.Latest, 143.9},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+	// Against the average on the 1st, a week before the latest price.
+	if g.Stats.WeekChange == nil || *g.Stats.WeekChange != 3.4 {
+		t.Errorf("week change = %v, want 3.4", g.Stats.WeekChange)
+	}
+	if groups[2].Stats.WeekChange != nil {
+		t.Errorf("got a week change of %v with only one day of prices", *groups[2].Stats.WeekChange)
+	}
+}
+
+func TestSummarizeGroupBy(t *testing.T) {
+	entries := []Entry{
+		{StationID: "a", Station: "ALPHA", Brand: "Shell", Postcode: "M1 1AE", FuelType: "E10", Price: 1400, RecordedAt: time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)},
+		{StationID: "b", Station: "BETA", Brand: "SHELL ", Postcode: "SW1A 1AA", FuelType: "E10", Price: 1420, RecordedAt: time.Date(2026, 9, 30, 23, 30, 0, 0, time.UTC)},
+		{StationID: "c", Station: "GAMMA", Brand: "BP", Postcode: "", FuelType: "E10", Price: 1410, RecordedAt: time.Date(2026, 9, 2, 9, 0, 0, 0, time.UTC)},
+	}
+	tests := []struct {
+		by   GroupBy
+		want string
+	}{
+		{GroupByStation, "ALPHA 1, BETA 1, GAMMA 1"},
+		{GroupByBrand, "BP 1, Shell 2"},
+		{GroupByOutward, "M1 1, SW1A 1, Unknown 1"},
+		// BETA's price was just after midnight on the 1st in the UK.
+		{GroupByMonth, "Sep 2026 1, Oct 2026 2"},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, g := range Summarize(entries, tt.by) {
+			got = append(got, fmt.Sprintf("%s %d", g.Label, g.Stats.Count))
+		}
+		if s := strings.Join(got, ", "); s != tt.want {
+			t.Errorf("by %s: got %s, want %s", tt.by, s, tt.want)
+		}
+	}
+}
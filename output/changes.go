@@ -0,0 +1,137 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/poolski/fueltracker/history"
+	"github.com/poolski/fueltracker/types"
+)
+
+// ChangesDocument is what the json and yaml formats write for changes. It
+// is versioned with SchemaVersion, like Document.
+type ChangesDocument struct {
+	SchemaVersion int       `json:"schema_version" yaml:"schema_version"`
+	PreviousAt    time.Time `json:"previous_at" yaml:"previous_at"`
+	CurrentAt     time.Time `json:"current_at" yaml:"current_at"`
+	Changes       []Change  `json:"changes" yaml:"changes"`
+}
+
+// Change is a difference between two lookups. The ndjson format writes one
+// per line. Prices are in pence per litre, and are null where they don't
+// apply, e.g. for a new station.
+type Change struct {
+	// Change is rise, drop, new_station, gone_station or fuel_gone.
+	Change     string   `json:"change" yaml:"change"`
+	StationID  string   `json:"station_id" yaml:"station_id"`
+	Station    string   `json:"station" yaml:"station"`
+	Brand      string   `json:"brand" yaml:"brand"`
+	FuelType   string   `json:"fuel_type,omitempty" yaml:"fuel_type,omitempty"`
+	WasPence   *float64 `json:"was_pence" yaml:"was_pence"`
+	NowPence   *float64 `json:"now_pence" yaml:"now_pence"`
+	DeltaPence *float64 `json:"delta_pence" yaml:"delta_pence"`
+}
+
+// changeLabels are how the table describes each kind of change.
+var changeLabels = map[history.ChangeKind]string{
+	history.Rise:        "Rise",
+	history.Drop:        "Drop",
+	history.NewStation:  "New station",
+	history.GoneStation: "Station gone",
+	history.FuelGone:    "No longer sold",
+}
+
+// NewChangesDocument converts the changes between prev and cur to the output
+// schema.
+func NewChangesDocument(prev, cur *history.Snapshot, changes []history.Change) ChangesDocument {
+	doc := ChangesDocument{
+		SchemaVersion: SchemaVersion,
+		PreviousAt:    prev.TakenAt,
+		CurrentAt:     cur.TakenAt,
+		Changes:       []Change{},
+	}
+	pence := func(p types.Price) *float64 {
+		if p == 0 {
+			return nil
+		}
+		v := p.Pence()
+		return &v
+	}
+	for _, c := range changes {
+		out := Change{
+			Change:    string(c.Kind),
+			StationID: c.StationID,
+			Station:   c.Station,
+			Brand:     c.Brand,
+			FuelType:  c.FuelType,
+			WasPence:  pence(c.Was),
+			NowPence:  pence(c.Now),
+		}
+		if c.Kind == history.Rise || c.Kind == history.Drop {
+			out.DeltaPence = pence(c.Delta())
+		}
+		doc.Changes = append(doc.Changes, out)
+	}
+	return doc
+}
+
+// RenderChanges writes the changes between prev and cur to w in format f.
+func RenderChanges(w io.Writer, f Format, prev, cur *history.Snapshot, changes []history.Change) error {
+	if f == Table {
+		writeChangesTable(w, changes)
+		return nil
+	}
+	doc := NewChangesDocument(prev, cur, changes)
+	return document[Change]{
+		Doc:    doc,
+		Items:  doc.Changes,
+		Header: changesCSVHeader,
+		Row:    changesCSVRow,
+	}.encode(w, f)
+}
+
+func writeChangesTable(w io.Writer, changes []history.Change) {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Change", "Station", "Brand", "Fuel Type", "Was", "Now", "Difference"})
+	price := func(p types.Price) string {
+		if p == 0 {
+			return "-"
+		}
+		return p.String()
+	}
+	for _, c := range changes {
+		diff := "-"
+		if c.Kind == history.Rise || c.Kind == history.Drop {
+			diff = fmt.Sprintf("%+.1fp", c.Delta().Pence())
+		}
+		table.Append([]string{
+			changeLabels[c.Kind],
+			c.Station,
+			c.Brand,
+			c.FuelType,
+			price(c.Was),
+			price(c.Now),
+			diff,
+		})
+	}
+	table.Render()
+}
+
+// changesCSVHeader is the first row of changes CSV output.
+var changesCSVHeader = []string{
+	"change", "station_id", "station", "brand", "fuel_type", "was_pence", "now_pence", "delta_pence",
+}
+
+func changesCSVRow(c Change) []string {
+	f := func(v *float64) string {
+		if v == nil {
+			return ""
+		}
+		return fmt.Sprintf("%.1f", *v)
+	}
+	return []string{
+		c.Change, c.StationID, c.Station, c.Brand, c.FuelType, f(c.WasPence), f(c.NowPence), f(c.DeltaPence),
+	}
+}
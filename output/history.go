@@ -0,0 +1,209 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/poolski/fueltracker/history"
+	"github.com/poolski/fueltracker/types"
+)
+
+// sparkWidth is the most days a sparkline shows. Older days are left off.
+const sparkWidth = 30
+
+// HistoryDocument is what the json and yaml formats write for history. It
+// is versioned with SchemaVersion, like Document.
+type HistoryDocument struct {
+	SchemaVersion int            `json:"schema_version" yaml:"schema_version"`
+	Groups        []HistoryGroup `json:"groups" yaml:"groups"`
+}
+
+// HistoryGroup is the statistics for one fuel in a group. The ndjson format
+// writes one per line. Prices are in pence per litre.
+type HistoryGroup struct {
+	// Group is the station, brand, outward postcode or month.
+	Group       string  `json:"group" yaml:"group"`
+	Key         string  `json:"key" yaml:"key"`
+	FuelType    string  `json:"fuel_type" yaml:"fuel_type"`
+	Count       int     `json:"count" yaml:"count"`
+	MinPence    float64 `json:"min_pence" yaml:"min_pence"`
+	MaxPence    float64 `json:"max_pence" yaml:"max_pence"`
+	MeanPence   float64 `json:"mean_pence" yaml:"mean_pence"`
+	MedianPence float64 `json:"median_pence" yaml:"median_pence"`
+	LatestPence float64 `json:"latest_pence" yaml:"latest_pence"`
+	// WeekChangePence is null if there's no price from a week ago.
+	WeekChangePence *float64 `json:"week_change_pence" yaml:"week_change_pence"`
+	// Daily is the average price on each day with a price.
+	Daily []HistoryDay `json:"daily" yaml:"daily"`
+	// Series is every price in the group, if asked for.
+	Series []HistoryPoint `json:"series,omitempty" yaml:"series,omitempty"`
+}
+
+// HistoryDay is the average price in a group on one day.
+type HistoryDay struct {
+	Date  string  `json:"date" yaml:"date"`
+	Pence float64 `json:"pence" yaml:"pence"`
+}
+
+// HistoryPoint is a price as reported by a station.
+type HistoryPoint struct {
+	RecordedAt time.Time `json:"recorded_at" yaml:"recorded_at"`
+	StationID  string    `json:"station_id" yaml:"station_id"`
+	Station    string    `json:"station" yaml:"station"`
+	Pence      float64   `json:"pence" yaml:"pence"`
+}
+
+// NewHistoryDocument converts groups to the output schema, with each price
+// listed if series is set.
+func NewHistoryDocument(groups []*history.Group, series bool) HistoryDocument {
+	doc := HistoryDocument{SchemaVersion: SchemaVersion, Groups: []HistoryGroup{}}
+	for _, g := range groups {
+		hg := HistoryGroup{
+			Group:           g.Label,
+			Key:             g.Key,
+			FuelType:        g.FuelType,
+			Count:           g.Stats.Count,
+			MinPence:        g.Stats.Min,
+			MaxPence:        g.Stats.Max,
+			MeanPence:       g.Stats.Mean,
+			MedianPence:     g.Stats.Median,
+			LatestPence:     g.Stats.Latest,
+			WeekChangePence: g.Stats.WeekChange,
+		}
+		for _, d := range g.Daily {
+			hg.Daily = append(hg.Daily, HistoryDay{Date: d.Date.Format("2006-01-02"), Pence: d.Pence})
+		}
+		if series {
+			for _, e := range g.Entries {
+				hg.Series = append(hg.Series, HistoryPoint{
+					RecordedAt: e.RecordedAt.In(types.London),
+					StationID:  e.StationID,
+					Station:    e.Station,
+					Pence:      e.Price.Pence(),
+				})
+			}
+		}
+		doc.Groups = append(doc.Groups, hg)
+	}
+	return doc
+}
+
+// RenderHistory writes groups to w in format f. With series set, every
+// price is listed as well as the statistics.
+func RenderHistory(w io.Writer, f Format, groups []*history.Group, series bool) error {
+	if f == Table {
+		if series {
+			writeHistorySeries(w, groups)
+		}
+		writeHistoryStats(w, groups)
+		return nil
+	}
+	doc := NewHistoryDocument(groups, series)
+	return document[HistoryGroup]{
+		Doc:    doc,
+		Items:  doc.Groups,
+		Header: historyCSVHeader,
+		Row:    historyCSVRow,
+	}.encode(w, f)
+}
+
+func writeHistorySeries(w io.Writer, groups []*history.Group) {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Recorded At", "Station", "Fuel Type", "Price"})
+	for _, g := range groups {
+		for _, e := range g.Entries {
+			table.Append([]string{
+				e.RecordedAt.In(types.London).Format("02/01/2006 15:04"),
+				e.Station,
+				e.FuelType,
+				e.Price.String(),
+			})
+		}
+	}
+	table.Render()
+}
+
+func writeHistoryStats(w io.Writer, groups []*history.Group) {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Group", "Fuel Type", "Prices", "Min", "Max", "Mean", "Median", "Latest", "Week Change", "Trend"})
+	for _, g := range groups {
+		s := g.Stats
+		change := "-"
+		if s.WeekChange != nil {
+			change = fmt.Sprintf("%+.1fp", *s.WeekChange)
+		}
+		values := make([]float64, len(g.Daily))
+		for i, d := range g.Daily {
+			values[i] = d.Pence
+		}
+		table.Append([]string{
+			g.Label,
+			g.FuelType,
+			strconv.Itoa(s.Count),
+			formatPence(s.Min),
+			formatPence(s.Max),
+			formatPence(s.Mean),
+			formatPence(s.Median),
+			formatPence(s.Latest),
+			change,
+			Sparkline(values, sparkWidth),
+		})
+	}
+	table.Render()
+}
+
+// historyCSVHeader is the first row of history CSV output. There is one row
+// per group, without the daily prices or series.
+var historyCSVHeader = []string{
+	"group", "key", "fuel_type", "count", "min_pence", "max_pence", "mean_pence", "median_pence", "latest_pence", "week_change_pence",
+}
+
+func historyCSVRow(g HistoryGroup) []string {
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', 1, 64) }
+	change := ""
+	if g.WeekChangePence != nil {
+		change = f(*g.WeekChangePence)
+	}
+	return []string{
+		g.Group, g.Key, g.FuelType, strconv.Itoa(g.Count),
+		f(g.MinPence), f(g.MaxPence), f(g.MeanPence), f(g.MedianPence), f(g.LatestPence), change,
+	}
+}
+
+func formatPence(pence float64) string {
+	return fmt.Sprintf("%.1fp", pence)
+}
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline draws values as a line of block characters, scaled between
+// the smallest and largest. Only the last width values are drawn.
+func Sparkline(values []float64, width int) string {
+	if len(values) > width {
+		values = values[len(values)-width:]
+	}
+	if len(values) == 0 {
+		return ""
+	}
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		if v < lo {
+			lo = v
+		}
+		if v > hi {
+			hi = v
+		}
+	}
+	out := make([]rune, len(values))
+	for i, v := range values {
+		level := 0
+		if hi > lo {
+			level = int((v - lo) / (hi - lo) * float64(len(sparkBlocks)-1))
+		}
+		out[i] = sparkBlocks[level]
+	}
+	return string(out)
+}
@@ -0,0 +1,25 @@
+package output
+
+import "testing"
+
+func TestSparkline(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		width  int
+		want   string
+	}{
+		{name: "empty", values: nil, width: 10, want: ""},
+		{name: "flat", values: []float64{140, 140, 140}, width: 10, want: "▁▁▁"},
+		{name: "low and high", values: []float64{139.9, 149.9}, width: 10, want: "▁█"},
+		{name: "every level", values: []float64{0, 1, 2, 3, 4, 5, 6, 7}, width: 10, want: "▁▂▃▄▅▆▇█"},
+		{name: "falling", values: []float64{7, 0, 7}, width: 10, want: "█▁█"},
+		// Only the latest values fit, and they are scaled on their own.
+		{name: "too wide", values: []float64{0, 100, 5, 6, 7}, width: 3, want: "▁▄█"},
+	}
+	for _, tt := range tests {
+		if got := Sparkline(tt.values, tt.width); got != tt.want {
+			t.Errorf("%s: Sparkline(%v, %d) = %q, want %q", tt.name, tt.values, tt.width, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+// Package output renders fuel prices for people and for other programs.
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/poolski/fueltracker/fueldata"
+	"github.com/poolski/fueltracker/types"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is a way of rendering prices.
+type Format string
+
+const (
+	Table  Format = "table"
+	JSON   Format = "json"
+	NDJSON Format = "ndjson"
+	CSV    Format = "csv"
+	YAML   Format = "yaml"
+)
+
+// Formats lists every Format, for help and error messages.
+var Formats = []Format{Table, JSON, NDJSON, CSV, YAML}
+
+// ParseFormat returns the Format named by s.
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if strings.EqualFold(s, string(f)) {
+			return f, nil
+		}
+	}
+	names := make([]string, len(Formats))
+	for i, f := range Formats {
+		names[i] = string(f)
+	}
+	return "", fmt.Errorf("unknown output format %q, valid formats are: %s", s, strings.Join(names, ", "))
+}
+
+// Renderer writes prices to w in some format.
+type Renderer interface {
+	Render(w io.Writer, records []*types.SpecificFuelPrice) error
+}
+
+// New returns the Renderer for f.
+func New(f Format) (Renderer, error) {
+	switch f {
+	case Table:
+		return tableRenderer{}, nil
+	case JSON, NDJSON, CSV, YAML:
+		return documentRenderer{f}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q", f)
+}
+
+// IsTable reports whether f is meant for people rather than programs.
+func (f Format) IsTable() bool {
+	return f == Table
+}
+
+type tableRenderer struct{}
+
+func (tableRenderer) Render(w io.Writer, records []*types.SpecificFuelPrice) error {
+	fueldata.WriteFuelPrices(w, records)
+	return nil
+}
+
+// documentRenderer renders prices in one of the formats for programs.
+type documentRenderer struct {
+	format Format
+}
+
+func (r documentRenderer) Render(w io.Writer, records []*types.SpecificFuelPrice) error {
+	doc := NewDocument(records)
+	return document[Price]{
+		Doc:    doc,
+		Items:  doc.Prices,
+		Header: csvHeader,
+		Row:    csvRow,
+	}.encode(w, r.format)
+}
+
+// document is what the formats for programs write: Doc as a whole for json
+// and yaml, each of Items on its own line for ndjson, and Header followed by
+// a Row for each of Items for csv.
+type document[T any] struct {
+	Doc    any
+	Items  []T
+	Header []string
+	Row    func(T) []string
+}
+
+// encode writes d to w in format f, which mustn't be Table.
+func (d document[T]) encode(w io.Writer, f Format) error {
+	switch f {
+	case JSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(d.Doc)
+	case NDJSON:
+		enc := json.NewEncoder(w)
+		for _, item := range d.Items {
+			if err := enc.Encode(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case YAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(d.Doc); err != nil {
+			return err
+		}
+		return enc.Close()
+	case CSV:
+		out := csv.NewWriter(w)
+		if err := out.Write(d.Header); err != nil {
+			return err
+		}
+		for _, item := range d.Items {
+			if err := out.Write(d.Row(item)); err != nil {
+				return err
+			}
+		}
+		out.Flush()
+		return out.Error()
+	}
+	return fmt.Errorf("unknown output format %q", f)
+}
+
+// csvHeader is the first row of CSV output. The columns match the fields
+// of Price, with the address flattened and amenities separated by ";".
+var csvHeader = []string{
+	"station_id", "station", "brand", "fuel_type", "price_pence", "recorded_at", "distance_miles",
+	"street", "suburb", "town", "county", "postcode", "latitude", "longitude", "amenities",
+}
+
+func csvRow(p Price) []string {
+	var price, recordedAt string
+	if p.PricePence != nil {
+		price = strconv.FormatFloat(*p.PricePence, 'f', 1, 64)
+	}
+	if p.RecordedAt != nil {
+		recordedAt = p.RecordedAt.Format(time.RFC3339)
+	}
+	return []string{
+		p.StationID,
+		p.Station,
+		p.Brand,
+		p.FuelType,
+		price,
+		recordedAt,
+		strconv.FormatFloat(p.DistanceMiles, 'f', -1, 64),
+		p.Address.Street,
+		p.Address.Suburb,
+		p.Address.Town,
+		p.Address.County,
+		p.Address.Postcode,
+		strconv.FormatFloat(p.Latitude, 'f', -1, 64),
+		strconv.FormatFloat(p.Longitude, 'f', -1, 64),
+		strings.Join(p.Amenities, ";"),
+	}
+}
@@ -0,0 +1,98 @@
+package output
+
+import (
+	"time"
+
+	"github.com/poolski/fueltracker/fueldata"
+	"github.com/poolski/fueltracker/types"
+)
+
+// SchemaVersion is the version of Document. It only changes when a field is
+// removed or changes meaning; new fields may be added without changing it.
+const SchemaVersion = 1
+
+// Document is what the json and yaml formats write.
+type Document struct {
+	SchemaVersion int     `json:"schema_version" yaml:"schema_version"`
+	Prices        []Price `json:"prices" yaml:"prices"`
+}
+
+// Price is a station's price for one fuel. The ndjson format writes one per
+// line.
+type Price struct {
+	// StationID is the stable ID from fueldata.StationID.
+	StationID string `json:"station_id" yaml:"station_id"`
+	Station   string `json:"station" yaml:"station"`
+	Brand     string `json:"brand" yaml:"brand"`
+	FuelType  string `json:"fuel_type" yaml:"fuel_type"`
+	// PricePence is per litre, or null if the station hasn't reported one,
+	// e.g. for EV charging.
+	PricePence *float64 `json:"price_pence" yaml:"price_pence"`
+	// RecordedAt is when the price was reported, in RFC 3339 format with
+	// the UK offset, or null if it isn't known.
+	RecordedAt *time.Time `json:"recorded_at" yaml:"recorded_at"`
+	// DistanceMiles is how far the station is from where was searched.
+	DistanceMiles float64 `json:"distance_miles" yaml:"distance_miles"`
+	Address       Address `json:"address" yaml:"address"`
+	Latitude      float64 `json:"latitude" yaml:"latitude"`
+	Longitude     float64 `json:"longitude" yaml:"longitude"`
+	// Amenities are names from fueldata.Amenities. It is never null.
+	Amenities []string `json:"amenities" yaml:"amenities"`
+}
+
+// Address is a station's address. Fields the provider doesn't report are
+// empty strings.
+type Address struct {
+	Street   string `json:"street" yaml:"street"`
+	Suburb   string `json:"suburb" yaml:"suburb"`
+	Town     string `json:"town" yaml:"town"`
+	County   string `json:"county" yaml:"county"`
+	Postcode string `json:"postcode" yaml:"postcode"`
+}
+
+// NewDocument converts records to the output schema. The placeholder
+// records used when nothing is found are left out, so that case is an empty
+// list.
+func NewDocument(records []*types.SpecificFuelPrice) Document {
+	doc := Document{SchemaVersion: SchemaVersion, Prices: []Price{}}
+	for _, r := range records {
+		if r.StationID == "" {
+			continue
+		}
+		doc.Prices = append(doc.Prices, NewPrice(r))
+	}
+	return doc
+}
+
+// NewPrice converts a record to the output schema.
+func NewPrice(r *types.SpecificFuelPrice) Price {
+	p := Price{
+		StationID:     r.StationID,
+		Station:       r.Station,
+		Brand:         r.Brand,
+		FuelType:      r.FuelType,
+		DistanceMiles: r.Distance,
+		Address: Address{
+			Street:   r.Street,
+			Suburb:   r.Suburb,
+			Town:     r.Town,
+			County:   r.County,
+			Postcode: r.Postcode,
+		},
+		Latitude:  r.Latitude,
+		Longitude: r.Longitude,
+		Amenities: fueldata.AmenityNames(r.Features),
+	}
+	if p.Amenities == nil {
+		p.Amenities = []string{}
+	}
+	if r.Price != 0 {
+		pence := r.Price.Pence()
+		p.PricePence = &pence
+	}
+	if !r.RecordedAt.IsZero() {
+		at := r.RecordedAt.In(types.London)
+		p.RecordedAt = &at
+	}
+	return p
+}
@@ -0,0 +1,85 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/poolski/fueltracker/types"
+)
+
+// templateFuncs are the helpers available to templates, on top of the
+// text/template built-ins.
+var templateFuncs = template.FuncMap{
+	// pence formats a price per litre in pence, e.g. "139.9p".
+	"pence": func(p types.Price) string { return p.String() },
+	// gbp formats a price per litre in pounds, e.g. "£1.399".
+	"gbp": func(p types.Price) string { return fmt.Sprintf("£%.3f", p.GBP()) },
+	// miles formats a distance, e.g. "1.2 mi".
+	"miles": func(d float64) string { return fmt.Sprintf("%.1f mi", d) },
+	// ago formats how long ago t was, e.g. "3h ago".
+	"ago": func(t time.Time) string { return ago(time.Now(), t) },
+	// pad pads s with spaces on the right to width characters.
+	"pad": func(width int, s string) string { return pad(width, s, false) },
+	// padLeft pads s with spaces on the left to width characters.
+	"padLeft": func(width int, s string) string { return pad(width, s, true) },
+	"upper":   strings.ToUpper,
+	"lower":   strings.ToLower,
+}
+
+type templateRenderer struct {
+	tmpl *template.Template
+}
+
+// NewTemplate returns a Renderer which executes a text/template with the
+// prices as its data, so "{{range .}}{{.Station}}: {{.Price}}{{end}}" lists
+// each station and its price. The placeholder records used when nothing is
+// found are left out, so "{{if not .}}" tests for that.
+func NewTemplate(text string) (Renderer, error) {
+	tmpl, err := template.New("output").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parsing template: %w", err)
+	}
+	return templateRenderer{tmpl: tmpl}, nil
+}
+
+func (t templateRenderer) Render(w io.Writer, records []*types.SpecificFuelPrice) error {
+	var found []*types.SpecificFuelPrice
+	for _, r := range records {
+		if r.StationID != "" {
+			found = append(found, r)
+		}
+	}
+	return t.tmpl.Execute(w, found)
+}
+
+// ago formats how long before now t was, to the largest whole unit.
+func ago(now, t time.Time) string {
+	if t.IsZero() {
+		return "unknown"
+	}
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d/time.Minute))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d/time.Hour))
+	}
+	return fmt.Sprintf("%dd ago", int(d/(24*time.Hour)))
+}
+
+func pad(width int, s string, left bool) string {
+	n := width - utf8.RuneCountInString(s)
+	if n <= 0 {
+		return s
+	}
+	if left {
+		return strings.Repeat(" ", n) + s
+	}
+	return s + strings.Repeat(" ", n)
+}
@@ -0,0 +1,198 @@
+package postcode
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/poolski/fueltracker/config"
+	"github.com/poolski/fueltracker/geo"
+)
+
+// ErrNoDirectory is returned when no postcode directory has been imported.
+var ErrNoDirectory = errors.New("no postcode directory has been imported, run 'fueltracker postcodes import <file>'")
+
+// Column names for the postcode and its location, in order of preference.
+// The ONS Postcode Directory uses pcds, lat and long; other lists tend to
+// use the longer names.
+var (
+	postcodeColumns  = []string{"pcds", "pcd2", "pcd", "postcode"}
+	latitudeColumns  = []string{"lat", "latitude"}
+	longitudeColumns = []string{"long", "lon", "lng", "longitude"}
+	// terminatedColumn is set in the ONS Postcode Directory for postcodes
+	// which are no longer in use.
+	terminatedColumn = "doterm"
+)
+
+// noLocation is the latitude the ONS Postcode Directory gives postcodes
+// without a grid reference.
+const noLocation = 99.999999
+
+// Directory is a list of postcodes and where they are, imported from the
+// ONS Postcode Directory so coordinates can be turned into a postcode
+// without a network call.
+type Directory struct {
+	Path string
+}
+
+// Entry is a postcode in the Directory.
+type Entry struct {
+	Postcode  string
+	Latitude  float64
+	Longitude float64
+}
+
+// DefaultDirectoryPath returns where the directory is kept if the config
+// doesn't say otherwise.
+func DefaultDirectoryPath() (string, error) {
+	dir, err := config.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "postcodes.csv"), nil
+}
+
+// Import reads a postcode CSV, such as the ONS Postcode Directory, and saves
+// the postcodes in use which have a location to d. It returns how many were
+// saved.
+func (d *Directory) Import(r io.Reader) (int, error) {
+	in := csv.NewReader(bufio.NewReader(r))
+	in.ReuseRecord = true
+	header, err := in.Read()
+	if err != nil {
+		return 0, fmt.Errorf("reading header: %w", err)
+	}
+	pcCol, latCol, lonCol := findColumn(header, postcodeColumns), findColumn(header, latitudeColumns), findColumn(header, longitudeColumns)
+	if pcCol < 0 || latCol < 0 || lonCol < 0 {
+		return 0, errors.New("the file needs postcode, latitude and longitude columns, e.g. the ONS Postcode Directory's pcds, lat and long")
+	}
+	termCol := findColumn(header, []string{terminatedColumn})
+
+	if err := os.MkdirAll(filepath.Dir(d.Path), 0o700); err != nil {
+		return 0, err
+	}
+	// Write to a temporary file first so a failed import leaves the old
+	// directory alone.
+	tmp, err := os.CreateTemp(filepath.Dir(d.Path), ".tmp-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	out := bufio.NewWriter(tmp)
+
+	n := 0
+	for {
+		rec, err := in.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			tmp.Close()
+			return 0, err
+		}
+		if termCol >= 0 && strings.TrimSpace(rec[termCol]) != "" {
+			continue
+		}
+		pc, err := Parse(rec[pcCol])
+		if err != nil {
+			continue
+		}
+		lat, latErr := strconv.ParseFloat(strings.TrimSpace(rec[latCol]), 64)
+		lon, lonErr := strconv.ParseFloat(strings.TrimSpace(rec[lonCol]), 64)
+		if latErr != nil || lonErr != nil || lat == noLocation || (lat == 0 && lon == 0) {
+			continue
+		}
+		fmt.Fprintf(out, "%s,%.6f,%.6f\n", pc.Compact(), lat, lon)
+		n++
+	}
+	if n == 0 {
+		tmp.Close()
+		return 0, errors.New("no postcodes with a location found")
+	}
+
+	if err := out.Flush(); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+	return n, os.Rename(tmp.Name(), d.Path)
+}
+
+// Nearest returns the postcode closest to the given coordinates and how far
+// away it is in miles.
+func (d *Directory) Nearest(lat, lon float64) (Entry, float64, error) {
+	f, err := os.Open(d.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Entry{}, 0, ErrNoDirectory
+	}
+	if err != nil {
+		return Entry{}, 0, err
+	}
+	defer f.Close()
+
+	var best Entry
+	bestDist := math.Inf(1)
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		e, ok := parseEntry(s.Text())
+		if !ok {
+			continue
+		}
+		// Most postcodes are nowhere near, so skip anything more than
+		// bestDist away in latitude alone (roughly 69 miles a degree)
+		// before doing the full calculation.
+		if math.Abs(e.Latitude-lat)*69 > bestDist {
+			continue
+		}
+		if dist := geo.Distance(lat, lon, e.Latitude, e.Longitude); dist < bestDist {
+			best, bestDist = e, dist
+		}
+	}
+	if err := s.Err(); err != nil {
+		return Entry{}, 0, fmt.Errorf("reading postcode directory %s: %w", d.Path, err)
+	}
+	if best.Postcode == "" {
+		return Entry{}, 0, ErrNoDirectory
+	}
+	return best, bestDist, nil
+}
+
+func parseEntry(line string) (Entry, bool) {
+	parts := strings.Split(line, ",")
+	if len(parts) != 3 {
+		return Entry{}, false
+	}
+	lat, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return Entry{}, false
+	}
+	lon, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil {
+		return Entry{}, false
+	}
+	return Entry{Postcode: parts[0], Latitude: lat, Longitude: lon}, true
+}
+
+// findColumn returns the index of the first of names in header, ignoring
+// case, or -1 if there isn't one.
+func findColumn(header []string, names []string) int {
+	for _, name := range names {
+		for i, h := range header {
+			// Files saved from Excel often start with a byte order mark.
+			h = strings.TrimPrefix(h, "\ufeff")
+			if strings.EqualFold(strings.TrimSpace(h), name) {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,85 @@
+// Package postcode parses and normalizes UK postcodes.
+package postcode
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalid is returned for anything which isn't a UK postcode.
+var ErrInvalid = errors.New("invalid postcode")
+
+// pattern is the UK postcode format. The outward code is an area of one or
+// two letters and a district, which is a number optionally followed by a
+// letter. The inward code is a sector digit and two unit letters. Some
+// letters are never used in some positions, e.g. no area starts with Q, V
+// or X, which catches a few more typos.
+var pattern = regexp.MustCompile(`^(` +
+	`[A-PR-UWYZ][0-9][0-9]?|` +
+	`[A-PR-UWYZ][A-HK-Y][0-9][0-9]?|` +
+	`[A-PR-UWYZ][0-9][A-HJKPSTUW]|` +
+	`[A-PR-UWYZ][A-HK-Y][0-9][ABEHMNPRVWXY]|` +
+	`GIR` +
+	`)([0-9][ABD-HJLNP-UW-Z]{2})$`)
+
+// Postcode is a UK postcode split into its outward code, which identifies
+// the town or district, and its inward code, which narrows that down to a
+// street or a few addresses.
+type Postcode struct {
+	Outward string
+	Inward  string
+}
+
+// Parse validates s as a UK postcode. Case and spacing don't matter, so
+// "sw1a1aa" and " SW1A  1AA" are both fine.
+func Parse(s string) (Postcode, error) {
+	compact := Compact(s)
+	m := pattern.FindStringSubmatch(compact)
+	if m == nil || (m[1] == "GIR" && m[2] != "0AA") {
+		if compact == "" {
+			return Postcode{}, fmt.Errorf("%w: no postcode given", ErrInvalid)
+		}
+		return Postcode{}, fmt.Errorf("%w %q, expected something like 'SW1A 1AA'", ErrInvalid, s)
+	}
+	return Postcode{Outward: m[1], Inward: m[2]}, nil
+}
+
+// Normalize returns s in the standard format, e.g. "SW1A 1AA".
+func Normalize(s string) (string, error) {
+	p, err := Parse(s)
+	if err != nil {
+		return "", err
+	}
+	return p.String(), nil
+}
+
+// Valid reports whether s is a UK postcode.
+func Valid(s string) bool {
+	_, err := Parse(s)
+	return err == nil
+}
+
+// Compact upper-cases s and removes any whitespace, without checking that
+// it's a valid postcode. It's for comparing postcodes from providers, which
+// aren't always well formed.
+func Compact(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return unicode.ToUpper(r)
+	}, s)
+}
+
+// String returns the postcode in the standard format, e.g. "SW1A 1AA".
+func (p Postcode) String() string {
+	return p.Outward + " " + p.Inward
+}
+
+// Compact returns the postcode without a space, e.g. "SW1A1AA".
+func (p Postcode) Compact() string {
+	return p.Outward + p.Inward
+}
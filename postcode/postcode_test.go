@@ -0,0 +1,105 @@
+package postcode
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in          string
+		outward     string
+		inward      string
+		wantInvalid bool
+	}{
+		{in: "M1 1AE", outward: "M1", inward: "1AE"},
+		{in: "B33 8TH", outward: "B33", inward: "8TH"},
+		{in: "CR2 6XH", outward: "CR2", inward: "6XH"},
+		{in: "DN55 1PT", outward: "DN55", inward: "1PT"},
+		{in: "W1A 0AX", outward: "W1A", inward: "0AX"},
+		{in: "EC1A 1BB", outward: "EC1A", inward: "1BB"},
+		{in: "GIR 0AA", outward: "GIR", inward: "0AA"},
+		{in: "sw1a1aa", outward: "SW1A", inward: "1AA"},
+		{in: " SW1A  1AA\t", outward: "SW1A", inward: "1AA"},
+		{in: "", wantInvalid: true},
+		{in: "   ", wantInvalid: true},
+		{in: "SW1A", wantInvalid: true},
+		{in: "12345", wantInvalid: true},
+		// No area starts with Q.
+		{in: "QA1 1AA", wantInvalid: true},
+		// C is never a unit letter.
+		{in: "SW1A 1AC", wantInvalid: true},
+		{in: "GIR 1AA", wantInvalid: true},
+		{in: "SW1A-1AA", wantInvalid: true},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if tt.wantInvalid {
+			if !errors.Is(err, ErrInvalid) {
+				t.Errorf("Parse(%q) = %v, %v, want ErrInvalid", tt.in, got, err)
+			}
+			if Valid(tt.in) {
+				t.Errorf("Valid(%q) = true", tt.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q) returned error %v", tt.in, err)
+			continue
+		}
+		if got.Outward != tt.outward || got.Inward != tt.inward {
+			t.Errorf("Parse(%q) = %q %q, want %q %q", tt.in, got.Outward, got.Inward, tt.outward, tt.inward)
+		}
+		if !Valid(tt.in) {
+			t.Errorf("Valid(%q) = false", tt.in)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in, want string
+		wantErr  bool
+	}{
+		{in: "sw1a1aa", want: "SW1A 1AA"},
+		{in: "SW1A  1AA", want: "SW1A 1AA"},
+		{in: "m11ae", want: "M1 1AE"},
+		{in: "dn551pt", want: "DN55 1PT"},
+		{in: "not a postcode", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := Normalize(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Normalize(%q) returned error %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCompact(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"SW1A 1AA", "SW1A1AA"},
+		{" sw1a\t1aa\n", "SW1A1AA"},
+		{"", ""},
+		// It doesn't check the postcode is valid.
+		{"not a postcode", "NOTAPOSTCODE"},
+	}
+	for _, tt := range tests {
+		if got := Compact(tt.in); got != tt.want {
+			t.Errorf("Compact(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	p, err := Parse("ec1a 1bb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p.Compact(); got != "EC1A1BB" {
+		t.Errorf("Postcode.Compact() = %q, want %q", got, "EC1A1BB")
+	}
+}
@@ -35,7 +35,18 @@ func (s *GSheets) Write(rec *types.SpecificFuelPrice) error {
 
 	var vr sheets.ValueRange
 
-	vals := []interface{}{rec.RecordedAt, rec.Station, rec.FuelType, rec.Price}
+	// New columns go on the end so existing sheets keep their layout. The
+	// station ID stays the same if the station is renamed, so it can be used
+	// to keep a station's rows together.
+	recordedAt := ""
+	if !rec.RecordedAt.IsZero() {
+		recordedAt = rec.RecordedAt.In(types.London).Format("02/01/2006")
+	}
+
+	vals := []interface{}{
+		recordedAt, rec.Station, rec.FuelType, rec.Price.GBP(), rec.StationID,
+		rec.Brand, rec.Distance, rec.Address(), rec.Latitude, rec.Longitude,
+	}
 	vr.Values = append(vr.Values, vals)
 
 	_, err := s.Service.Spreadsheets.Values.Append(
@@ -1,5 +1,14 @@
 package types
 
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	// Embed the time zone database so London is always available.
+	_ "time/tzdata"
+)
+
 type RawAPIResponse struct {
 	Response FuelDataResponse `json:"Response,omitempty"`
 }
@@ -23,35 +32,41 @@ type FuelDataResponse struct {
 }
 
 type FuelStation struct {
-	DistanceFromSearchPostcode float64 `json:"DistanceFromSearchPostcode,omitempty"`
-	Brand                      string  `json:"Brand,omitempty"`
-	Name                       string  `json:"Name,omitempty"`
-	Street                     string  `json:"Street,omitempty"`
-	Suburb                     string  `json:"Suburb,omitempty"`
-	Town                       string  `json:"Town,omitempty"`
-	County                     string  `json:"County,omitempty"`
-	Postcode                   string  `json:"Postcode,omitempty"`
-	Latitude                   float64 `json:"Latitude,omitempty"`
-	Longitude                  float64 `json:"Longitude,omitempty"`
-	Features                   struct {
-		Fuel struct {
-			HasUnleaded      bool `json:"HasUnleaded,omitempty"`
-			HasSuperUnleaded bool `json:"HasSuperUnleaded,omitempty"`
-			HasDiesel        bool `json:"HasDiesel,omitempty"`
-			HasPremiumDiesel bool `json:"HasPremiumDiesel,omitempty"`
-			HasLpg           bool `json:"HasLpg,omitempty"`
-			HasEvCharging    bool `json:"HasEvCharging,omitempty"`
-		} `json:"Fuel,omitempty"`
-		Services struct {
-			HasCarWash   bool `json:"HasCarWash,omitempty"`
-			HasTyrePump  bool `json:"HasTyrePump,omitempty"`
-			HasWater     bool `json:"HasWater,omitempty"`
-			HasCashPoint bool `json:"HasCashPoint,omitempty"`
-			HasCarVacuum bool `json:"HasCarVacuum,omitempty"`
-		} `json:"Services,omitempty"`
-	} `json:"Features,omitempty"`
-	FuelPriceCount int         `json:"FuelPriceCount,omitempty"`
-	FuelPriceList  []FuelPrice `json:"FuelPriceList,omitempty"`
+	DistanceFromSearchPostcode float64         `json:"DistanceFromSearchPostcode,omitempty"`
+	Brand                      string          `json:"Brand,omitempty"`
+	Name                       string          `json:"Name,omitempty"`
+	Street                     string          `json:"Street,omitempty"`
+	Suburb                     string          `json:"Suburb,omitempty"`
+	Town                       string          `json:"Town,omitempty"`
+	County                     string          `json:"County,omitempty"`
+	Postcode                   string          `json:"Postcode,omitempty"`
+	Latitude                   float64         `json:"Latitude,omitempty"`
+	Longitude                  float64         `json:"Longitude,omitempty"`
+	Features                   StationFeatures `json:"Features,omitempty"`
+	FuelPriceCount             int             `json:"FuelPriceCount,omitempty"`
+	FuelPriceList              []FuelPrice     `json:"FuelPriceList,omitempty"`
+}
+
+type StationFeatures struct {
+	Fuel     FuelFeatures    `json:"Fuel,omitempty"`
+	Services ServiceFeatures `json:"Services,omitempty"`
+}
+
+type FuelFeatures struct {
+	HasUnleaded      bool `json:"HasUnleaded,omitempty"`
+	HasSuperUnleaded bool `json:"HasSuperUnleaded,omitempty"`
+	HasDiesel        bool `json:"HasDiesel,omitempty"`
+	HasPremiumDiesel bool `json:"HasPremiumDiesel,omitempty"`
+	HasLpg           bool `json:"HasLpg,omitempty"`
+	HasEvCharging    bool `json:"HasEvCharging,omitempty"`
+}
+
+type ServiceFeatures struct {
+	HasCarWash   bool `json:"HasCarWash,omitempty"`
+	HasTyrePump  bool `json:"HasTyrePump,omitempty"`
+	HasWater     bool `json:"HasWater,omitempty"`
+	HasCashPoint bool `json:"HasCashPoint,omitempty"`
+	HasCarVacuum bool `json:"HasCarVacuum,omitempty"`
 }
 
 type FuelPrice struct {
@@ -62,10 +77,83 @@ type FuelPrice struct {
 		TimeRecorded string  `json:"TimeRecorded,omitempty"`
 	} `json:"LatestRecordedPrice,omitempty"`
 }
+
+// London is the time zone prices are reported in.
+var London = mustLoadLocation("Europe/London")
+
+func mustLoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
+// Price is an amount of money in tenths of a penny, the precision UK fuel
+// prices are quoted in, so that arithmetic on prices is exact.
+type Price int64
+
+// PriceFromPence converts a price in pence, e.g. 139.9, to a Price.
+func PriceFromPence(pence float64) Price {
+	return Price(math.Round(pence * 10))
+}
+
+// PriceFromGBP converts a price in pounds, e.g. 1.399, to a Price.
+func PriceFromGBP(gbp float64) Price {
+	return Price(math.Round(gbp * 1000))
+}
+
+func (p Price) Pence() float64 {
+	return float64(p) / 10
+}
+
+func (p Price) GBP() float64 {
+	return float64(p) / 1000
+}
+
+// String formats the price in pence, e.g. "139.9p".
+func (p Price) String() string {
+	return fmt.Sprintf("%.1fp", p.Pence())
+}
+
 type SpecificFuelPrice struct {
-	Station    string
-	FuelType   string
-	Price      float64
-	RecordedAt string
-	MonthYear  string
+	Station   string
+	StationID string
+	FuelType  string
+	// Price is per litre. It is zero if the station has not reported one.
+	Price Price
+	// RecordedAt is when the station reported the price, in Europe/London.
+	// It is zero if the station has not reported one.
+	RecordedAt time.Time
+
+	// Details of the station the price is for.
+	Brand     string
+	Distance  float64 // miles from the search postcode
+	Street    string
+	Suburb    string
+	Town      string
+	County    string
+	Postcode  string
+	Latitude  float64
+	Longitude float64
+	Features  StationFeatures
+}
+
+// Age returns how old the price was at now, or zero if it's not known.
+func (s *SpecificFuelPrice) Age(now time.Time) time.Duration {
+	if s.RecordedAt.IsZero() {
+		return 0
+	}
+	return now.Sub(s.RecordedAt)
+}
+
+// Address returns the station's address on one line.
+func (s *SpecificFuelPrice) Address() string {
+	var parts []string
+	for _, p := range []string{s.Street, s.Suburb, s.Town, s.County, s.Postcode} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, ", ")
 }